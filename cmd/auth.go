@@ -0,0 +1,25 @@
+package cmd
+
+import "github.com/spf13/cobra"
+
+// AuthCmd groups the authentication commands
+var AuthCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Google 계정 인증을 관리합니다.",
+	Long:  `Google 계정 인증을 관리합니다.`,
+}
+
+// AuthLoginCmd represents the auth login command
+var AuthLoginCmd = &cobra.Command{
+	Use:          "login",
+	Short:        "CLIENT_SECRET_FILE 로 OAuth 로그인을 하고 토큰을 저장합니다.",
+	Long:         "CLIENT_SECRET_FILE 과 SCOPES 로 OAuth2 installed-app 로그인을 진행하고\nrefresh token 을 TOKEN_FILE (기본값 .ga4bq-token.json) 에 저장합니다.",
+	SilenceUsage: true,
+	RunE:         app.AuthLoginE,
+}
+
+func init() {
+	AuthLoginCmd.Flags().Bool("device", false, "use the device authorization flow instead of a loopback redirect")
+	AuthCmd.AddCommand(AuthLoginCmd)
+	rootCmd.AddCommand(AuthCmd)
+}
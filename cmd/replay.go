@@ -0,0 +1,18 @@
+package cmd
+
+import "github.com/spf13/cobra"
+
+// ReplayCmd represents the replay command
+var ReplayCmd = &cobra.Command{
+	Use:          "replay",
+	Short:        "보관된 GA4 응답으로 변환과 적재를 다시 실행합니다.",
+	Long:         "ARCHIVE_DIR 에 보관된 GA4 원본 응답으로 TransformData 와 적재 단계를 다시 실행합니다.\nGA4 API 는 호출하지 않습니다.",
+	PreRunE:      app.SetConfig,
+	RunE:         app.RunE,
+	SilenceUsage: true,
+}
+
+func init() {
+	ReplayCmd.Flags().Bool("serve-metrics-after-run", false, "with METRICS_ADDR, keep serving /metrics after the run until SIGINT/SIGTERM")
+	rootCmd.AddCommand(ReplayCmd)
+}
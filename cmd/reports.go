@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"go-ga4-to-bigquery/internal"
+)
+
+// ListReportsCmd represents the list-reports command
+var ListReportsCmd = &cobra.Command{
+	Use:          "list-reports",
+	Short:        "지원하는 리포트 타입과 설명을 출력합니다.",
+	Long:         `REPORT_TYPES 에 쓸 수 있는 리포트 타입과 설명을 출력합니다.`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE:         app.ListReportsE,
+}
+
+// DescribeReportCmd represents the describe-report command
+var DescribeReportCmd = &cobra.Command{
+	Use:          "describe-report <type>",
+	Short:        "리포트의 요청, 스키마, 적재 테이블을 출력합니다.",
+	Long:         "리포트의 dimension, metric, filter, BigQuery 스키마, natural key, 테이블 이름과 출력별 파티션 구조를 출력합니다.\n테이블 이름은 설정 파일의 TABLE_PREFIX 를 따릅니다.",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE:         app.DescribeReportE,
+}
+
+func init() {
+	for _, c := range []*cobra.Command{ListReportsCmd, DescribeReportCmd} {
+		c.Flags().String("format", internal.FormatTable, "output format: table or json")
+		rootCmd.AddCommand(c)
+	}
+}
@@ -4,17 +4,16 @@ Copyright © 2024 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
-	"fmt"
 	"go-ga4-to-bigquery/internal"
+	"go-ga4-to-bigquery/internal/config"
+	"go-ga4-to-bigquery/pkg/ga4bq"
 	"os"
 
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 )
 
 var (
-	app     = internal.NewApp()
-	cfgFile string
+	app = internal.NewApp()
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -41,40 +40,23 @@ var rootCmd = &cobra.Command{
 func Execute() {
 	err := rootCmd.Execute()
 	if err != nil {
-		os.Exit(1)
+		os.Exit(ga4bq.ExitCode(err))
 	}
 }
 
-func init() {
-	cobra.OnInitialize(initConfig)
+// ExecuteWith runs the CLI with extra pipeline options, for programs that embed it with
+// their own sinks or fetcher. Private reports are added with ga4bq.RegisterReport.
+func ExecuteWith(opts ...ga4bq.Option) {
+	app.AddOptions(opts...)
+	Execute()
+}
 
-	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is config.json)")
+func init() {
+	// --config, --profile 및 설정 키 덮어쓰기 플래그는 모든 하위 명령에서 공유합니다.
+	config.BindFlags(rootCmd.PersistentFlags())
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
 	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 
 }
-
-func initConfig() {
-	if cfgFile != "" {
-		// Use config file from the flag.
-		viper.SetConfigFile(cfgFile)
-	} else {
-		// Find home directory.
-		home, err := os.Getwd()
-		cobra.CheckErr(err)
-
-		// Search config in home directory with name ".cobra" (without extension).
-		viper.AddConfigPath(home)
-		viper.SetConfigType("json")
-		viper.SetConfigName(".local")
-	}
-
-	viper.AutomaticEnv()
-
-	if err := viper.ReadInConfig(); err == nil {
-		fmt.Println("Using config file:", viper.ConfigFileUsed())
-		fmt.Println("config:", viper.AllSettings())
-	}
-}
@@ -4,14 +4,17 @@ import "github.com/spf13/cobra"
 
 // event represents the event command
 var RunReportCmd = &cobra.Command{
-	Use:     "run-report",
-	Short:   "기본 리포트를 생성 및 전송합니다.",
-	Long:    `기본 리포트를 생성 및 전송합니다.`,
-	PreRunE: app.SetConfig,
-	RunE:    app.RunE,
+	Use:          "run-report",
+	Short:        "기본 리포트를 생성 및 전송합니다.",
+	Long:         `기본 리포트를 생성 및 전송합니다.`,
+	PreRunE:      app.SetConfig,
+	RunE:         app.RunE,
+	SilenceUsage: true,
 }
 
 func init() {
-	RunReportCmd.Flags().StringVar(&cfgFile, "config", "", "config file (default is config.json)")
+	RunReportCmd.Flags().Bool("dry-run", false, "print the GA4 requests and the tables/DDL each output would get as JSON, without writing anything")
+	RunReportCmd.Flags().Bool("count-rows", false, "with --dry-run, run the GA4 requests to count rows")
+	RunReportCmd.Flags().Bool("serve-metrics-after-run", false, "with METRICS_ADDR, keep serving /metrics after the run until SIGINT/SIGTERM")
 	rootCmd.AddCommand(RunReportCmd)
 }
@@ -0,0 +1,16 @@
+package cmd
+
+import "github.com/spf13/cobra"
+
+// ValidateConfigCmd represents the validate-config command
+var ValidateConfigCmd = &cobra.Command{
+	Use:          "validate-config",
+	Short:        "설정 파일을 검증하고 모든 문제를 출력합니다.",
+	Long:         `설정 파일을 검증하고 모든 문제를 출력합니다.`,
+	SilenceUsage: true,
+	RunE:         app.ValidateConfigE,
+}
+
+func init() {
+	rootCmd.AddCommand(ValidateConfigCmd)
+}
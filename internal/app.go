@@ -3,153 +3,270 @@ package internal
 import (
 	"context"
 	"fmt"
-	"log"
-	"os"
+	"io"
+	"log/slog"
 	"os/signal"
 	"syscall"
 
-	"cloud.google.com/go/bigquery"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 	ga "google.golang.org/api/analyticsdata/v1beta"
 	"google.golang.org/api/option"
 
+	"go-ga4-to-bigquery/internal/auth"
+	"go-ga4-to-bigquery/internal/config"
+	"go-ga4-to-bigquery/internal/logging"
+	"go-ga4-to-bigquery/internal/metrics"
 	"go-ga4-to-bigquery/internal/reports"
-	"go-ga4-to-bigquery/internal/reports/impl"
+	_ "go-ga4-to-bigquery/internal/reports/impl" // registers the built-in reports
+	"go-ga4-to-bigquery/pkg/ga4bq"
 )
 
-type Config struct {
-	ReportTypes          []string `json:"REPORT_TYPES"`
-	ClientSecretFile     string   `json:"CLIENT_SECRET_FILE"`
-	ServiceAccountFile   string   `json:"SERVICE_ACCOUNT_FILE"`
-	Scopes               []string `json:"SCOPES"`
-	PropertyID           string   `json:"PROPERTY_ID"`
-	InitialFetchFromDate string   `json:"INITIAL_FETCH_FROM_DATE"`
-	FetchToDate          string   `json:"FETCH_TO_DATE"`
-	ProjectId            string   `json:"PROJECT_ID"`
-	DatasetID            string   `json:"DATASET_ID"`
-	TablePrefix          string   `json:"TABLE_PREFIX"`
-	PartitionBy          string   `json:"PARTITION_BY"`
-	ClusterBy            string   `json:"CLUSTER_BY"`
+type App struct {
+	cfg      *config.Config
+	registry *reports.Registry
+	options  []ga4bq.Option
+	pipeline *ga4bq.Pipeline
 }
 
-func (c Config) AllConfig() string {
-	return fmt.Sprintf("%+v", c)
+func NewApp(opts ...ga4bq.Option) *App {
+	return &App{registry: reports.Default, options: opts}
 }
 
-type App struct {
-	cfg                *Config
-	ga4DataFetcher     *Ga4DataFetcher
-	ga4DataTransformer *Ga4DataTransformer
-	bigQueryDateInsert *BigQueryDateInserter
+// SetRegistry replaces the report registry, e.g. with one holding a program's private reports.
+func (a *App) SetRegistry(r *reports.Registry) {
+	a.registry = r
 }
 
-func NewApp() *App {
-	return &App{}
+// AddOptions adds pipeline options, e.g. ga4bq.WithSink, applied when the pipeline is built.
+func (a *App) AddOptions(opts ...ga4bq.Option) {
+	a.options = append(a.options, opts...)
 }
 
+// SetConfig loads and validates the config selected by the command's flags.
 func (a *App) SetConfig(cmd *cobra.Command, args []string) error {
-	var err error
+	if err := a.loadConfig(cmd); err != nil {
+		return &ga4bq.StageError{Stage: ga4bq.StageConfig, Err: err}
+	}
+	if err := a.cfg.Validate(a.registry.Names()); err != nil {
+		return &ga4bq.StageError{Stage: ga4bq.StageConfig, Err: errors.Wrap(err, "invalid config")}
+	}
+	slog.Debug("Loaded config", "config", a.cfg.AllConfig())
+
+	return nil
+}
 
-	cfgFile, err := cmd.Flags().GetString("config")
+// loadConfig loads the config without validating it, for commands that only need part of it.
+// Logging is set up from LOG_LEVEL and LOG_FORMAT first, on the command's stderr.
+func (a *App) loadConfig(cmd *cobra.Command) error {
+	cfg, file, err := config.New(config.OptionsFromFlags(cmd.Flags()))
 	if err != nil {
 		return err
 	}
-	if cfgFile != "" {
-		// Use config file from the flag.
-		viper.SetConfigFile(cfgFile)
-	} else {
-		// Find home directory.
-		home, err := os.Getwd()
-		cobra.CheckErr(err)
-
-		// Search config in home directory with name ".cobra" (without extension).
-		viper.AddConfigPath(home)
-		viper.SetConfigType("json")
-		viper.SetConfigName(".local")
-	}
-
-	viper.AutomaticEnv()
-
-	if err := viper.ReadInConfig(); err == nil {
-		fmt.Println("Using config file:", viper.ConfigFileUsed())
-		a.cfg = &Config{
-			ReportTypes:          viper.GetStringSlice("REPORT_TYPES"),
-			ClientSecretFile:     viper.GetString("CLIENT_SECRET_FILE"),
-			ServiceAccountFile:   viper.GetString("SERVICE_ACCOUNT_FILE"),
-			Scopes:               viper.GetStringSlice("SCOPES"),
-			PropertyID:           viper.GetString("PROPERTY_ID"),
-			ProjectId:            viper.GetString("PROJECT_ID"),
-			InitialFetchFromDate: viper.GetString("INITIAL_FETCH_FROM_DATE"),
-			FetchToDate:          viper.GetString("FETCH_TO_DATE"),
-			DatasetID:            viper.GetString("DATASET_ID"),
-			TablePrefix:          viper.GetString("TABLE_PREFIX"),
-		}
-		fmt.Println(a.cfg.AllConfig())
-	} else {
-		return errors.Wrap(err, "failed to read config")
+	if err := logging.Setup(cmd.ErrOrStderr(), cfg.LogFormat, cfg.LogLevel); err != nil {
+		return err
 	}
-
+	if file != "" {
+		slog.Info("Using config file", "file", file)
+	}
+	a.cfg = cfg
 	return nil
 }
 
-// Run runs the Ga4DataFetcher
-func (a *App) RunE(cmd *cobra.Command, args []string) error {
-	ctx := context.Background()
+// AuthLoginE runs the OAuth installed-app flow with CLIENT_SECRET_FILE and caches the token in TOKEN_FILE.
+func (a *App) AuthLoginE(cmd *cobra.Command, args []string) error {
+	if err := a.loadConfig(cmd); err != nil {
+		return &ga4bq.StageError{Stage: ga4bq.StageConfig, Err: err}
+	}
+	if a.cfg.ClientSecretFile == "" {
+		return &ga4bq.StageError{Stage: ga4bq.StageConfig, Err: errors.New("CLIENT_SECRET_FILE is required for auth login")}
+	}
+	oauthCfg, err := auth.OAuthConfig(a.cfg.ClientSecretFile, a.cfg.Scopes)
+	if err != nil {
+		return err
+	}
 
-	// Create a new Google Analytics Data service
-	gaService, err := ga.NewService(ctx, option.WithCredentialsFile(a.cfg.ServiceAccountFile))
+	device, err := cmd.Flags().GetBool("device")
+	if err != nil {
+		return err
+	}
+	login := auth.LoginLoopback
+	if device {
+		login = auth.LoginDevice
+	}
+	tok, err := login(cmd.Context(), oauthCfg, cmd.OutOrStdout())
 	if err != nil {
-		log.Printf("Failed to create Google Analytics service: %v", err)
+		return errors.Wrap(err, "failed to log in")
 	}
-	// Settup GA4 client
-	a.ga4DataFetcher = NewGa4DataFetcher(gaService)
 
-	// Create a new Transformer
-	a.ga4DataTransformer = NewGa4DataTransformer()
+	tokenFile := auth.TokenFile(a.cfg)
+	if err := auth.SaveToken(tokenFile, tok); err != nil {
+		return err
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), "Token saved to", tokenFile)
+	return nil
+}
 
-	// Create a new BigQuery client
-	bqClient, err := bigquery.NewClient(ctx, a.cfg.ProjectId, option.WithCredentialsFile(a.cfg.ServiceAccountFile))
-	if err != nil {
-		log.Printf("Failed to create BigQuery client: %v", err)
+// ValidateConfigE loads the config and prints every validation problem at once.
+func (a *App) ValidateConfigE(cmd *cobra.Command, args []string) error {
+	if err := a.SetConfig(cmd, args); err != nil {
+		var verrs config.ValidationErrors
+		if errors.As(err, &verrs) {
+			for _, e := range verrs {
+				fmt.Fprintln(cmd.OutOrStdout(), "-", e.Error())
+			}
+			return &ga4bq.StageError{Stage: ga4bq.StageConfig, Err: errors.Errorf("config has %d problem(s)", len(verrs))}
+		}
+		return err
 	}
+	fmt.Fprintln(cmd.OutOrStdout(), "config is valid")
+	return nil
+}
 
-	// BigQuery client
-	a.bigQueryDateInsert = NewBigQueryDateInsert(bqClient)
+// RunE builds the clients, verifies access and runs the command. SIGINT/SIGTERM cancel the run.
+// With METRICS_ADDR and --serve-metrics-after-run, /metrics keeps being served after the
+// run until SIGINT/SIGTERM, so that Prometheus can scrape the final values; the run's
+// error is returned then. Without the flag the command exits when the run finishes.
+func (a *App) RunE(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(cmd.Context(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	defer a.Close()
+
+	// metrics 서버는 ctx 가 끝나면, 즉 명령이 끝나거나 SIGINT/SIGTERM 을 받으면 종료됩니다.
+	if a.cfg.MetricsAddr != "" {
+		addr, err := metrics.Serve(ctx, a.cfg.MetricsAddr)
+		if err != nil {
+			return &ga4bq.StageError{Stage: ga4bq.StageConfig, Err: errors.Wrap(err, "failed to serve metrics")}
+		}
+		slog.Info("Serving Prometheus metrics", "url", "http://"+addr.String()+"/metrics")
+	}
 
+	err := a.runCommand(ctx, cmd)
+	serveAfter, _ := cmd.Flags().GetBool("serve-metrics-after-run")
+	if serveAfter && a.cfg.MetricsAddr != "" && ctx.Err() == nil {
+		slog.Info("Run finished; serving metrics until SIGINT or SIGTERM")
+		<-ctx.Done()
+	}
+	return err
+}
+
+// runCommand runs run-report or replay.
+func (a *App) runCommand(ctx context.Context, cmd *cobra.Command) error {
 	// 상위 Command는 Google Analytics Data API를 이용하여 데이터를 조회 하는 방식을 결정합니다.
 	switch cmd.Use {
 	case "run-report":
-		return a.Run()
+		if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+			countRows, _ := cmd.Flags().GetBool("count-rows")
+			return a.DryRun(ctx, cmd.OutOrStdout(), countRows)
+		}
+		if err := a.Start(ctx); err != nil {
+			return err
+		}
+		return a.Run(ctx)
+	case "replay":
+		// replay 는 보관된 응답만 사용하므로 GA4 클라이언트가 필요 없습니다.
+		if err := a.StartSinks(ctx); err != nil {
+			return err
+		}
+		return a.Replay(ctx)
 	default:
-		errors.Wrap(err, "invalid command")
+		return errors.Errorf("invalid command %q", cmd.Use)
 	}
-	sigc := make(chan os.Signal, 1)
-	signal.Notify(sigc, syscall.SIGINT, syscall.SIGTERM)
-	<-sigc
+}
+
+// newPipeline builds the pipeline for the loaded config; the App's registry comes first
+// so that options added by an embedding program can still replace it.
+func (a *App) newPipeline() error {
+	opts := append([]ga4bq.Option{ga4bq.WithRegistry(a.registry)}, a.options...)
+	pipeline, err := ga4bq.New(a.cfg, opts...)
+	if err != nil {
+		return err
+	}
+	a.pipeline = pipeline
 	return nil
 }
 
-func (a *App) Run() error {
-	for _, reportType := range a.cfg.ReportTypes {
-		report, err := SelectReport(REPORT_TYPE(reportType))
-		if err != nil {
-			return errors.Wrap(err, "failed to select report")
+// Start builds the GA4 client and the sinks and checks that both sides are reachable
+// with the configured credentials before any report runs.
+func (a *App) Start(ctx context.Context) error {
+	if err := a.newPipeline(); err != nil {
+		return err
+	}
+	return a.pipeline.Open(ctx)
+}
+
+// StartSinks builds only the sinks, for commands that do not call GA4.
+func (a *App) StartSinks(ctx context.Context) error {
+	if err := a.newPipeline(); err != nil {
+		return err
+	}
+	return a.pipeline.OpenSinks(ctx)
+}
+
+// Close releases the clients and sinks created by Start.
+func (a *App) Close() error {
+	if a.pipeline == nil {
+		return nil
+	}
+	return a.pipeline.Close()
+}
+
+func (a *App) Run(ctx context.Context) error {
+	summary, err := a.pipeline.Run(ctx)
+	printSummary(summary)
+	return err
+}
+
+// DryRun prints the requests and output plans of every configured report as JSON.
+// Nothing is created or written; GA4 is only called with countRows.
+func (a *App) DryRun(ctx context.Context, w io.Writer, countRows bool) error {
+	if err := a.newPipeline(); err != nil {
+		return err
+	}
+	plan, err := a.pipeline.DryRun(ctx, countRows)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, plan)
+}
+
+// Replay re-runs the transform and load stages for every archived response of the
+// configured reports, without calling GA4.
+func (a *App) Replay(ctx context.Context) error {
+	summary, err := a.pipeline.Replay(ctx)
+	printSummary(summary)
+	return err
+}
+
+func printSummary(summary *ga4bq.Summary) {
+	if summary == nil || len(summary.Reports) == 0 {
+		return
+	}
+	hits := 0
+	for _, s := range summary.Reports {
+		source := "api"
+		if s.Cached {
+			source = "cache"
+			hits++
 		}
-		err = a.runReport(report)
-		if err != nil {
-			return errors.Wrap(err, "failed to run report")
+		if s.Err != nil {
+			slog.Error("Report failed", "report", s.ReportType, "error", s.Err)
+			continue
 		}
+		slog.Info("Report finished", "report", s.ReportType, "rows", s.Rows, "source", source, "reconciled", len(s.Reconciliation))
 	}
-	return nil
+	attrs := []any{"reports", len(summary.Reports)}
+	if summary.CacheEnabled {
+		attrs = append(attrs, "cache_hits", hits)
+	}
+	slog.Info("Run summary", attrs...)
 }
 
 func createServiceClient(ctx context.Context, serviceAccountFilePath string) (*ga.Service, error) {
 	// Use the service account file to authenticate and create a service client
 	service, err := ga.NewService(ctx, option.WithCredentialsFile(serviceAccountFilePath))
 	if err != nil {
-		log.Printf("Failed to create service: %v", err)
+		slog.Error("Failed to create service", "error", err)
 		return nil, err
 	}
 
@@ -160,31 +277,10 @@ func (a *App) Auth(ctx context.Context, serviceAccountFilePath string) (*ga.Serv
 	return createServiceClient(ctx, serviceAccountFilePath)
 }
 
-func (a *App) runReport(report reports.Report) error {
-	// Get the data from Google Analytics
-	result, err := a.ga4DataFetcher.GetGADataFetcher(a.cfg.PropertyID, a.cfg.InitialFetchFromDate, a.cfg.FetchToDate, report.ReportRequestFunc)
-	if err != nil {
-		return errors.Wrap(err, "failed to get GA data")
-	}
-
-	// Transform the data
-	transformedData, err := a.ga4DataTransformer.TransformData(result, report.TransformFunc)
-	if err != nil {
-		return errors.Wrap(err, "failed to transform data")
-	}
-
-	//Load the data into BigQuery
-	fullTableID := a.cfg.TablePrefix + report.ReportTitle()
-
-	err = a.bigQueryDateInsert.InsertData(context.Background(), a.bigQueryDateInsert.bqClient, a.cfg.DatasetID, fullTableID, report, transformedData)
-	if err != nil {
-		return errors.Wrap(err, "failed to load data into BigQuery")
-	}
-	return nil
-}
-
 type REPORT_TYPE string
 
+// Built-in report types. Reports are looked up in the registry; these remain for callers
+// that referred to the built-in types by constant.
 const (
 	ACTIVE_USERS          REPORT_TYPE = "daily-active-users" // active-users, user-technology, events
 	EVENTS                REPORT_TYPE = "daily-events"
@@ -193,23 +289,12 @@ const (
 	CROSS_CAMPAIGN        REPORT_TYPE = "daily-cross-channel"
 )
 
-func SelectReport(rType REPORT_TYPE) (reports.Report, error) {
-	switch rType {
-	case ACTIVE_USERS:
-
-		return &impl.ActiveUsersReport{}, nil
-	case EVENTS:
-
-		return &impl.EventsReport{}, nil
-	case USER_TECHNOLOGY:
-
-		return &impl.UserTechnologyReport{}, nil
-	case USER_CHANNEL_GROUPING:
+// ReportTypes returns every report type registered in reports.Default.
+func ReportTypes() []string {
+	return reports.Default.Names()
+}
 
-		return &impl.UserChannelGroupingReport{}, nil
-	case CROSS_CAMPAIGN:
-		return &impl.CrossChannelReport{}, nil
-	default:
-		return nil, errors.New("invalid report type")
-	}
+// SelectReport returns the report registered in reports.Default as rType.
+func SelectReport(rType REPORT_TYPE) (reports.Report, error) {
+	return reports.Default.New(string(rType))
 }
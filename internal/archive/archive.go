@@ -0,0 +1,112 @@
+package archive
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	ga "google.golang.org/api/analyticsdata/v1beta"
+
+	"go-ga4-to-bigquery/internal/atomicfile"
+)
+
+// Store 는 GA4 원본 응답을 <dir>/<property id>/<report type>/<start>_<end>.json 으로 보관합니다.
+// 날짜는 YYYY-MM-DD 로 변환된 값이어야 같은 키로 다시 찾을 수 있습니다.
+type Store struct {
+	dir string
+}
+
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Entry identifies one archived response.
+type Entry struct {
+	PropertyID string
+	ReportType string
+	StartDate  string
+	EndDate    string
+	// Table is the table the response was loaded into. It is kept next to the response
+	// in <start>_<end>.meta.json and is empty for responses archived before it was.
+	Table string
+}
+
+// metaSuffix ends the file holding an entry's metadata; List skips these files.
+const metaSuffix = ".meta.json"
+
+// entryMeta is the content of an entry's metadata file.
+type entryMeta struct {
+	Table string `json:"table"`
+}
+
+func (s *Store) path(e Entry) string {
+	return filepath.Join(s.dir, e.PropertyID, e.ReportType, e.StartDate+"_"+e.EndDate+".json")
+}
+
+func (s *Store) metaPath(e Entry) string {
+	return strings.TrimSuffix(s.path(e), ".json") + metaSuffix
+}
+
+// Save writes resp under e, replacing an earlier response for the same key.
+func (s *Store) Save(e Entry, resp *ga.RunReportResponse) error {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode response")
+	}
+	if err := atomicfile.WriteFile(s.path(e), data); err != nil {
+		return errors.Wrap(err, "failed to write archive file")
+	}
+	if data, err = json.Marshal(entryMeta{Table: e.Table}); err != nil {
+		return errors.Wrap(err, "failed to encode archive metadata")
+	}
+	return errors.Wrap(atomicfile.WriteFile(s.metaPath(e), data), "failed to write archive metadata")
+}
+
+// Load reads the response archived under e.
+func (s *Store) Load(e Entry) (*ga.RunReportResponse, error) {
+	data, err := os.ReadFile(s.path(e))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read archived response")
+	}
+	resp := &ga.RunReportResponse{}
+	if err := json.Unmarshal(data, resp); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode %s", s.path(e))
+	}
+	return resp, nil
+}
+
+// List returns the archived entries of one property and report type, oldest range first.
+func (s *Store) List(propertyID, reportType string) ([]Entry, error) {
+	files, err := filepath.Glob(filepath.Join(s.dir, propertyID, reportType, "*_*.json"))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list archive")
+	}
+	sort.Strings(files)
+	entries := make([]Entry, 0, len(files))
+	for _, f := range files {
+		if strings.HasSuffix(f, metaSuffix) {
+			continue
+		}
+		start, end, ok := strings.Cut(strings.TrimSuffix(filepath.Base(f), ".json"), "_")
+		if !ok {
+			continue
+		}
+		e := Entry{PropertyID: propertyID, ReportType: reportType, StartDate: start, EndDate: end}
+		data, err := os.ReadFile(s.metaPath(e))
+		switch {
+		case err == nil:
+			var meta entryMeta
+			if err := json.Unmarshal(data, &meta); err != nil {
+				return nil, errors.Wrapf(err, "failed to decode %s", s.metaPath(e))
+			}
+			e.Table = meta.Table
+		case !os.IsNotExist(err):
+			return nil, errors.Wrap(err, "failed to read archive metadata")
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
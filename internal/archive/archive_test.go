@@ -0,0 +1,37 @@
+package archive
+
+import (
+	"testing"
+
+	ga "google.golang.org/api/analyticsdata/v1beta"
+)
+
+func TestStore(t *testing.T) {
+	store := NewStore(t.TempDir())
+	entries := []Entry{
+		{PropertyID: "123", ReportType: "daily-events", StartDate: "2024-02-01", EndDate: "2024-02-29", Table: "ga4_daily_events_report_20240301"},
+		{PropertyID: "123", ReportType: "daily-events", StartDate: "2024-01-01", EndDate: "2024-01-31", Table: "ga4_daily_events_report_20240201"},
+	}
+	for i, e := range entries {
+		resp := &ga.RunReportResponse{RowCount: int64(i + 1), Rows: []*ga.Row{{DimensionValues: []*ga.DimensionValue{{Value: "page_view"}}}}}
+		if err := store.Save(e, resp); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+
+	got, err := store.List("123", "daily-events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0] != entries[1] || got[1] != entries[0] {
+		t.Fatalf("List() = %v, want oldest range first", got)
+	}
+
+	resp, err := store.Load(got[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.RowCount != 1 || resp.Rows[0].DimensionValues[0].Value != "page_view" {
+		t.Errorf("Load() = %+v", resp)
+	}
+}
@@ -0,0 +1,70 @@
+// Package atomicfile writes files through a temporary file in the same directory that
+// is renamed into place, so readers never see a partly written file.
+package atomicfile
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// File is a temporary file that becomes path on Commit.
+type File struct {
+	*os.File
+	path string
+}
+
+// Create creates the directory of path and a temporary file next to path.
+func Create(path string) (*File, error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, errors.Wrap(err, "failed to create directory")
+	}
+	file, err := os.CreateTemp(dir, "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create temporary file")
+	}
+	return &File{File: file, path: path}, nil
+}
+
+// Path returns the path the file is renamed to on Commit.
+func (f *File) Path() string {
+	return f.path
+}
+
+// Commit closes the temporary file, unless a writer already did, and renames it to
+// Path, replacing any file there.
+func (f *File) Commit() error {
+	if err := f.Close(); err != nil && !errors.Is(err, os.ErrClosed) {
+		os.Remove(f.Name())
+		return errors.Wrap(err, "failed to close file")
+	}
+	if err := os.Rename(f.Name(), f.path); err != nil {
+		os.Remove(f.Name())
+		return errors.Wrap(err, "failed to move file into place")
+	}
+	return nil
+}
+
+// Abort closes and removes the temporary file. It is safe to call after Commit.
+func (f *File) Abort() error {
+	f.Close()
+	if err := os.Remove(f.Name()); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "failed to remove temporary file")
+	}
+	return nil
+}
+
+// WriteFile writes data to path through a temporary file.
+func WriteFile(path string, data []byte) error {
+	f, err := Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Abort()
+		return errors.Wrap(err, "failed to write file")
+	}
+	return f.Commit()
+}
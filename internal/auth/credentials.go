@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"google.golang.org/api/impersonate"
+	"google.golang.org/api/option"
+
+	"go-ga4-to-bigquery/internal/config"
+)
+
+const (
+	GA4Scope      = "https://www.googleapis.com/auth/analytics.readonly"
+	BigQueryScope = "https://www.googleapis.com/auth/bigquery"
+)
+
+// ClientOptions builds the client options for one Google API client from its resolved
+// AuthConfig. scopes are only used where the strategy mints its own tokens (impersonate).
+func ClientOptions(ctx context.Context, cfg *config.Config, a config.AuthConfig, scopes ...string) ([]option.ClientOption, error) {
+	switch a.Strategy {
+	case config.AuthServiceAccountFile:
+		return []option.ClientOption{option.WithCredentialsFile(a.ServiceAccountFile)}, nil
+	case config.AuthADC:
+		// 옵션이 없으면 클라이언트 라이브러리가 Application Default Credentials 를 찾습니다.
+		return nil, nil
+	case config.AuthImpersonate:
+		var base []option.ClientOption
+		if a.ServiceAccountFile != "" {
+			base = append(base, option.WithCredentialsFile(a.ServiceAccountFile))
+		}
+		ts, err := impersonate.CredentialsTokenSource(ctx, impersonate.CredentialsConfig{
+			TargetPrincipal: a.TargetPrincipal,
+			Delegates:       a.Delegates,
+			Scopes:          scopes,
+		}, base...)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to impersonate %s", a.TargetPrincipal)
+		}
+		return []option.ClientOption{option.WithTokenSource(ts)}, nil
+	case config.AuthOAuthToken:
+		oauthCfg, err := OAuthConfig(cfg.ClientSecretFile, cfg.Scopes)
+		if err != nil {
+			return nil, err
+		}
+		ts, err := CachedTokenSource(ctx, oauthCfg, TokenFile(cfg))
+		if err != nil {
+			return nil, err
+		}
+		return []option.ClientOption{option.WithTokenSource(ts)}, nil
+	default:
+		return nil, errors.Errorf("unknown auth strategy %q", a.Strategy)
+	}
+}
+
+// TokenFile returns TOKEN_FILE, or DefaultTokenFile when it is not set.
+func TokenFile(cfg *config.Config) string {
+	if cfg.TokenFile != "" {
+		return cfg.TokenFile
+	}
+	return DefaultTokenFile
+}
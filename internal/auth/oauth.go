@@ -0,0 +1,176 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// DefaultScopes 는 SCOPES 가 비어 있을 때 사용하는 스코프입니다. GA4 조회와 BigQuery 적재에 모두 필요합니다.
+var DefaultScopes = []string{GA4Scope, BigQueryScope}
+
+// DefaultTokenFile is where the OAuth refresh token is cached when TOKEN_FILE is not set.
+const DefaultTokenFile = ".ga4bq-token.json"
+
+// OAuthConfig builds an installed-app OAuth2 config from a client secret file.
+func OAuthConfig(clientSecretFile string, scopes []string) (*oauth2.Config, error) {
+	data, err := os.ReadFile(clientSecretFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read client secret file")
+	}
+	if len(scopes) == 0 {
+		scopes = DefaultScopes
+	}
+	cfg, err := google.ConfigFromJSON(data, scopes...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse client secret file")
+	}
+	return cfg, nil
+}
+
+// LoginLoopback runs the installed-app flow: the user opens the printed URL and
+// Google redirects the authorization code back to a listener on 127.0.0.1.
+func LoginLoopback(ctx context.Context, cfg *oauth2.Config, out io.Writer) (*oauth2.Token, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start loopback listener")
+	}
+	defer listener.Close()
+
+	c := *cfg
+	c.RedirectURL = fmt.Sprintf("http://%s/", listener.Addr().String())
+
+	state := oauth2.GenerateVerifier()
+	verifier := oauth2.GenerateVerifier()
+
+	type result struct {
+		code string
+		err  error
+	}
+	results := make(chan result, 1)
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		switch {
+		case q.Get("state") != state:
+			http.Error(w, "state mismatch", http.StatusBadRequest)
+			return
+		case q.Get("error") != "":
+			results <- result{err: errors.Errorf("authorization denied: %s", q.Get("error"))}
+		default:
+			results <- result{code: q.Get("code")}
+		}
+		fmt.Fprintln(w, "Authentication finished. You can close this window.")
+	})}
+	go srv.Serve(listener)
+	defer srv.Close()
+
+	fmt.Fprintf(out, "Open the following URL in your browser to authorize:\n\n%s\n\n",
+		c.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.ApprovalForce, oauth2.S256ChallengeOption(verifier)))
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-results:
+		if res.err != nil {
+			return nil, res.err
+		}
+		tok, err := c.Exchange(ctx, res.code, oauth2.VerifierOption(verifier))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to exchange authorization code")
+		}
+		return tok, nil
+	}
+}
+
+// LoginDevice runs the device authorization flow. The client must be of the
+// "TVs and Limited Input devices" type for Google to accept it.
+func LoginDevice(ctx context.Context, cfg *oauth2.Config, out io.Writer) (*oauth2.Token, error) {
+	da, err := cfg.DeviceAuth(ctx, oauth2.AccessTypeOffline)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start device authorization")
+	}
+	fmt.Fprintf(out, "Visit %s and enter the code %s\n", da.VerificationURI, da.UserCode)
+
+	tok, err := cfg.DeviceAccessToken(ctx, da)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to obtain device token")
+	}
+	return tok, nil
+}
+
+// SaveToken writes the token to path, readable only by the current user.
+func SaveToken(path string, tok *oauth2.Token) error {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return errors.Wrap(err, "failed to create token directory")
+		}
+	}
+	data, err := json.MarshalIndent(tok, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to encode token")
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return errors.Wrap(err, "failed to write token file")
+	}
+	return nil
+}
+
+// LoadToken reads a token previously written by SaveToken.
+func LoadToken(path string) (*oauth2.Token, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read token file, run `auth login` first")
+	}
+	tok := &oauth2.Token{}
+	if err := json.Unmarshal(data, tok); err != nil {
+		return nil, errors.Wrap(err, "failed to decode token file")
+	}
+	return tok, nil
+}
+
+// CachedTokenSource returns a token source backed by the cached token. Refreshed
+// tokens are written back to path so the refresh token stays current.
+func CachedTokenSource(ctx context.Context, cfg *oauth2.Config, path string) (oauth2.TokenSource, error) {
+	tok, err := LoadToken(path)
+	if err != nil {
+		return nil, err
+	}
+	return &persistingTokenSource{
+		base: cfg.TokenSource(ctx, tok),
+		path: path,
+		last: tok.AccessToken,
+	}, nil
+}
+
+type persistingTokenSource struct {
+	mu   sync.Mutex
+	base oauth2.TokenSource
+	path string
+	last string
+}
+
+func (p *persistingTokenSource) Token() (*oauth2.Token, error) {
+	tok, err := p.base.Token()
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if tok.AccessToken != p.last {
+		p.last = tok.AccessToken
+		if err := SaveToken(p.path, tok); err != nil {
+			return nil, err
+		}
+	}
+	return tok, nil
+}
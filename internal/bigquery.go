@@ -3,11 +3,10 @@ package internal
 import (
 	"context"
 	"fmt"
+	"log/slog"
 
 	"cloud.google.com/go/bigquery"
 	"github.com/pkg/errors"
-
-	"go-ga4-to-bigquery/internal/reports"
 )
 
 type BigQueryDataLoader struct {
@@ -37,7 +36,7 @@ func selectQuery(ctx context.Context, bqClient *bigquery.Client, fullTableId str
 		if err != nil {
 			break
 		}
-		fmt.Printf("Row %d: %v\n", idx, row)
+		slog.Debug("Queried row", "table", fullTableId, "index", idx, "row", row)
 		idx++
 	}
 	return nil
@@ -53,29 +52,5 @@ func (b *BigQueryDataLoader) QueryTable(ctx context.Context, client *bigquery.Cl
 	return nil
 }
 
-type BigQueryDateInserter struct {
-	bqClient *bigquery.Client
-}
-
-func NewBigQueryDateInsert(client *bigquery.Client) *BigQueryDateInserter {
-	return &BigQueryDateInserter{
-		bqClient: client,
-	}
-}
-
-func (b *BigQueryDateInserter) InsertData(ctx context.Context, client *bigquery.Client, datasetID, tableID string, schemaGen reports.SchemaGenerator, data []bigquery.ValueSaver) error {
-	if err := client.Dataset(datasetID).Table(tableID).Create(ctx, &bigquery.TableMetadata{
-		Schema:   schemaGen.Schema(),
-		Location: "US",
-	}); err != nil {
-		return errors.Wrap(err, "failed to create table")
-	}
-
-	if err := client.Dataset(datasetID).Table(tableID).Inserter().Put(ctx, data); err != nil {
-		return errors.Wrap(err, "failed to insert data")
-	}
-	return nil
-}
-
 type BigQueryDataInserter struct {
 }
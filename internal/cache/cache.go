@@ -0,0 +1,107 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	ga "google.golang.org/api/analyticsdata/v1beta"
+
+	"go-ga4-to-bigquery/internal/atomicfile"
+)
+
+// Cache 는 GA4 응답을 요청 내용의 해시로 저장하는 디스크 캐시입니다.
+type Cache struct {
+	dir    string
+	policy Policy
+	now    func() time.Time
+}
+
+// Policy decides how long a response stays valid based on the last date it covers.
+// Data older than StableAfter days is final in GA4 and never expires; anything newer,
+// such as today or yesterday, is still being processed and expires after TTL.
+type Policy struct {
+	TTL         time.Duration
+	StableAfter int
+}
+
+// DefaultPolicy keeps recent data for 15 minutes and treats data older than 3 days as final.
+var DefaultPolicy = Policy{TTL: 15 * time.Minute, StableAfter: 3}
+
+func New(dir string, policy Policy) *Cache {
+	if policy.TTL <= 0 {
+		policy.TTL = DefaultPolicy.TTL
+	}
+	if policy.StableAfter <= 0 {
+		policy.StableAfter = DefaultPolicy.StableAfter
+	}
+	return &Cache{dir: dir, policy: policy, now: time.Now}
+}
+
+type entry struct {
+	// ExpiresAt is zero for responses that never expire.
+	ExpiresAt time.Time             `json:"expires_at"`
+	Response  *ga.RunReportResponse `json:"response"`
+}
+
+// Key hashes the full request. Relative dates must be resolved before hashing,
+// otherwise "today" would hit yesterday's entry.
+func Key(propertyID string, request *ga.RunReportRequest) (string, error) {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to encode request")
+	}
+	sum := sha256.Sum256(append([]byte(propertyID+"\n"), data...))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, key[:2], key+".json")
+}
+
+// Get returns the cached response for key unless it is missing or expired.
+func (c *Cache) Get(key string) (*ga.RunReportResponse, bool) {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+	var e entry
+	if err := json.Unmarshal(data, &e); err != nil || e.Response == nil {
+		return nil, false
+	}
+	if !e.ExpiresAt.IsZero() && c.now().After(e.ExpiresAt) {
+		return nil, false
+	}
+	return e.Response, true
+}
+
+// Put stores resp under key. endDate (YYYY-MM-DD) is the last day the response covers.
+func (c *Cache) Put(key, endDate string, resp *ga.RunReportResponse) error {
+	e := entry{Response: resp}
+	if ttl, forever := c.policy.TTLFor(endDate, c.now()); !forever {
+		e.ExpiresAt = c.now().Add(ttl)
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode cache entry")
+	}
+	return errors.Wrap(atomicfile.WriteFile(c.path(key), data), "failed to write cache file")
+}
+
+// TTLFor returns how long a response ending on endDate stays valid, or forever when
+// the date is older than StableAfter days. Unparseable dates get the short TTL.
+func (p Policy) TTLFor(endDate string, now time.Time) (ttl time.Duration, forever bool) {
+	end, err := time.ParseInLocation("2006-01-02", endDate, now.Location())
+	if err != nil {
+		return p.TTL, false
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if end.Before(today.AddDate(0, 0, -p.StableAfter)) {
+		return 0, true
+	}
+	return p.TTL, false
+}
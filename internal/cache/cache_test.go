@@ -0,0 +1,64 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	ga "google.golang.org/api/analyticsdata/v1beta"
+)
+
+func TestPolicy_TTLFor(t *testing.T) {
+	now := time.Date(2024, 3, 10, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		endDate     string
+		wantForever bool
+	}{
+		{endDate: "2024-03-10", wantForever: false},
+		{endDate: "2024-03-09", wantForever: false},
+		{endDate: "2024-03-07", wantForever: false},
+		{endDate: "2024-03-06", wantForever: true},
+		{endDate: "2023-01-01", wantForever: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.endDate, func(t *testing.T) {
+			ttl, forever := DefaultPolicy.TTLFor(tt.endDate, now)
+			if forever != tt.wantForever {
+				t.Errorf("TTLFor() forever = %v, want %v", forever, tt.wantForever)
+			}
+			if !forever && ttl != DefaultPolicy.TTL {
+				t.Errorf("TTLFor() ttl = %v, want %v", ttl, DefaultPolicy.TTL)
+			}
+		})
+	}
+}
+
+func TestCache(t *testing.T) {
+	now := time.Date(2024, 3, 10, 9, 0, 0, 0, time.UTC)
+	c := New(t.TempDir(), DefaultPolicy)
+	c.now = func() time.Time { return now }
+
+	request := func(end string) *ga.RunReportRequest {
+		return &ga.RunReportRequest{DateRanges: []*ga.DateRange{{StartDate: "2024-01-01", EndDate: end}}}
+	}
+	oldKey, _ := Key("123", request("2024-01-31"))
+	todayKey, _ := Key("123", request("2024-03-10"))
+	if oldKey == todayKey {
+		t.Fatal("Key() ignores the request contents")
+	}
+
+	resp := &ga.RunReportResponse{RowCount: 7}
+	if err := c.Put(oldKey, "2024-01-31", resp); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Put(todayKey, "2024-03-10", resp); err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(24 * time.Hour)
+	if got, ok := c.Get(oldKey); !ok || got.RowCount != 7 {
+		t.Errorf("Get(old) = %v, %v; want cached forever", got, ok)
+	}
+	if _, ok := c.Get(todayKey); ok {
+		t.Error("Get(today) hit after the TTL passed")
+	}
+}
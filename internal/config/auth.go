@@ -0,0 +1,53 @@
+package config
+
+// 인증 방식 (AUTH.STRATEGY)
+const (
+	AuthServiceAccountFile = "service_account_file"
+	AuthADC                = "adc"
+	AuthImpersonate        = "impersonate"
+	AuthOAuthToken         = "oauth_token"
+)
+
+// AuthStrategies lists every supported AUTH.STRATEGY value.
+var AuthStrategies = []string{AuthServiceAccountFile, AuthADC, AuthImpersonate, AuthOAuthToken}
+
+// AuthConfig 는 하나의 Google API 클라이언트가 사용할 자격 증명입니다.
+type AuthConfig struct {
+	Strategy           string   `json:"STRATEGY" mapstructure:"STRATEGY"`
+	ServiceAccountFile string   `json:"SERVICE_ACCOUNT_FILE" mapstructure:"SERVICE_ACCOUNT_FILE" secret:"true"`
+	TargetPrincipal    string   `json:"TARGET_PRINCIPAL" mapstructure:"TARGET_PRINCIPAL"`
+	Delegates          []string `json:"DELEGATES" mapstructure:"DELEGATES"`
+}
+
+// GA4Auth returns the credentials for the GA4 Data API client.
+func (c *Config) GA4Auth() AuthConfig {
+	return c.resolveAuth(c.GA4AuthOverride)
+}
+
+// BigQueryAuth returns the credentials for the BigQuery client.
+func (c *Config) BigQueryAuth() AuthConfig {
+	return c.resolveAuth(c.BigQueryAuthOverride)
+}
+
+// resolveAuth prefers the per-client section, then AUTH, then infers a strategy
+// from the legacy SERVICE_ACCOUNT_FILE / CLIENT_SECRET_FILE keys.
+func (c *Config) resolveAuth(side AuthConfig) AuthConfig {
+	a := side
+	if a.Strategy == "" {
+		a = c.Auth
+	}
+	if a.ServiceAccountFile == "" {
+		a.ServiceAccountFile = c.ServiceAccountFile
+	}
+	if a.Strategy == "" {
+		switch {
+		case a.ServiceAccountFile != "":
+			a.Strategy = AuthServiceAccountFile
+		case c.ClientSecretFile != "":
+			a.Strategy = AuthOAuthToken
+		default:
+			a.Strategy = AuthADC
+		}
+	}
+	return a
+}
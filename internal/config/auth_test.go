@@ -0,0 +1,50 @@
+package config
+
+import "testing"
+
+func TestConfig_ResolveAuth(t *testing.T) {
+	tests := []struct {
+		name         string
+		cfg          Config
+		wantGA4      string
+		wantBigQuery string
+	}{
+		{
+			name:         "legacy service account file",
+			cfg:          Config{ServiceAccountFile: "sa.json"},
+			wantGA4:      AuthServiceAccountFile,
+			wantBigQuery: AuthServiceAccountFile,
+		},
+		{
+			name:         "client secret only",
+			cfg:          Config{ClientSecretFile: "client.json"},
+			wantGA4:      AuthOAuthToken,
+			wantBigQuery: AuthOAuthToken,
+		},
+		{
+			name:         "nothing configured",
+			wantGA4:      AuthADC,
+			wantBigQuery: AuthADC,
+		},
+		{
+			name: "separate identities",
+			cfg: Config{
+				Auth:                 AuthConfig{Strategy: AuthADC},
+				GA4AuthOverride:      AuthConfig{Strategy: AuthImpersonate, TargetPrincipal: "reader@p.iam.gserviceaccount.com"},
+				BigQueryAuthOverride: AuthConfig{},
+			},
+			wantGA4:      AuthImpersonate,
+			wantBigQuery: AuthADC,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cfg.GA4Auth().Strategy; got != tt.wantGA4 {
+				t.Errorf("GA4Auth().Strategy = %v, want %v", got, tt.wantGA4)
+			}
+			if got := tt.cfg.BigQueryAuth().Strategy; got != tt.wantBigQuery {
+				t.Errorf("BigQueryAuth().Strategy = %v, want %v", got, tt.wantBigQuery)
+			}
+		})
+	}
+}
@@ -0,0 +1,370 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// EnvPrefix 는 모든 설정 키를 덮어쓸 수 있는 환경 변수의 접두사입니다. (예: GA4BQ_PROPERTY_ID)
+const EnvPrefix = "GA4BQ"
+
+const redacted = "[REDACTED]"
+
+// Config 는 설정 파일에서 읽어들인 실행 설정입니다.
+type Config struct {
+	ReportTypes          []string `json:"REPORT_TYPES" mapstructure:"REPORT_TYPES"`
+	ClientSecretFile     string   `json:"CLIENT_SECRET_FILE" mapstructure:"CLIENT_SECRET_FILE" secret:"true"`
+	ServiceAccountFile   string   `json:"SERVICE_ACCOUNT_FILE" mapstructure:"SERVICE_ACCOUNT_FILE" secret:"true"`
+	TokenFile            string   `json:"TOKEN_FILE" mapstructure:"TOKEN_FILE" secret:"true"`
+	Scopes               []string `json:"SCOPES" mapstructure:"SCOPES"`
+	PropertyID           string   `json:"PROPERTY_ID" mapstructure:"PROPERTY_ID"`
+	InitialFetchFromDate string   `json:"INITIAL_FETCH_FROM_DATE" mapstructure:"INITIAL_FETCH_FROM_DATE"`
+	FetchToDate          string   `json:"FETCH_TO_DATE" mapstructure:"FETCH_TO_DATE"`
+	ProjectId            string   `json:"PROJECT_ID" mapstructure:"PROJECT_ID"`
+	DatasetID            string   `json:"DATASET_ID" mapstructure:"DATASET_ID"`
+	// DatasetLocation 은 CREATE_DATASET 으로 새로 만드는 데이터셋의 위치입니다. (예: asia-northeast3)
+	// 이미 있는 데이터셋과 위치가 다르면 실행을 멈춥니다.
+	DatasetLocation        string        `json:"DATASET_LOCATION" mapstructure:"DATASET_LOCATION"`
+	CreateDataset          bool          `json:"CREATE_DATASET" mapstructure:"CREATE_DATASET"`
+	DefaultTableExpiration time.Duration `json:"DEFAULT_TABLE_EXPIRATION" mapstructure:"DEFAULT_TABLE_EXPIRATION"`
+	TablePrefix            string        `json:"TABLE_PREFIX" mapstructure:"TABLE_PREFIX"`
+	Output                 []string      `json:"OUTPUT" mapstructure:"OUTPUT"`
+	OutDir                 string        `json:"OUT_DIR" mapstructure:"OUT_DIR"`
+	Compression            string        `json:"COMPRESSION" mapstructure:"COMPRESSION"`
+	PostgresDSN            string        `json:"POSTGRES_DSN" mapstructure:"POSTGRES_DSN" secret:"true"`
+	PostgresSchema         string        `json:"POSTGRES_SCHEMA" mapstructure:"POSTGRES_SCHEMA"`
+	DBPath                 string        `json:"DB_PATH" mapstructure:"DB_PATH"`
+	Webhook                WebhookConfig `json:"WEBHOOK" mapstructure:"WEBHOOK"`
+
+	// ArchiveDir 가 있으면 GA4 원본 응답을 보관하고, replay 는 여기서 응답을 읽습니다.
+	ArchiveDir string `json:"ARCHIVE_DIR" mapstructure:"ARCHIVE_DIR"`
+
+	// FullRefresh 에 있는 리포트는 BigQuery 에 staging 테이블로 적재한 뒤 테이블을 통째로 교체합니다.
+	FullRefresh []string `json:"FULL_REFRESH" mapstructure:"FULL_REFRESH"`
+
+	// AllowBreaking 이면 스키마를 그대로 바꿀 수 없는 BigQuery 테이블을 다시 만듭니다. (기존 행은 삭제)
+	AllowBreaking bool `json:"ALLOW_BREAKING" mapstructure:"ALLOW_BREAKING"`
+
+	// Reconcile 이 켜져 있으면 적재 후 BigQuery 합계를 GA4 TOTAL 과 비교합니다.
+	Reconcile ReconcileConfig `json:"RECONCILE" mapstructure:"RECONCILE"`
+
+	// LogLevel 은 debug, info, warn, error 중 하나이고, 행 데이터는 debug 에서만 기록됩니다.
+	LogLevel  string `json:"LOG_LEVEL" mapstructure:"LOG_LEVEL"`
+	LogFormat string `json:"LOG_FORMAT" mapstructure:"LOG_FORMAT"`
+
+	// MetricsAddr 가 있으면 명령이 실행되는 동안 Prometheus /metrics 를 제공합니다. (예: :9090)
+	MetricsAddr string `json:"METRICS_ADDR" mapstructure:"METRICS_ADDR"`
+
+	// Cache.DIR 가 있으면 같은 요청에 대해 GA4 API 를 다시 호출하지 않습니다.
+	Cache CacheConfig `json:"CACHE" mapstructure:"CACHE"`
+
+	// ReportOutput 은 리포트 타입별로 OUTPUT 을 덮어씁니다. (예: {"daily-events": ["bigquery", "csv"]})
+	ReportOutput map[string][]string `json:"REPORT_OUTPUT" mapstructure:"REPORT_OUTPUT"`
+
+	// Auth 는 두 클라이언트의 기본 인증 방식이고, GA4_AUTH / BIGQUERY_AUTH 에 STRATEGY 가 있으면 그 쪽이 우선합니다.
+	Auth                 AuthConfig `json:"AUTH" mapstructure:"AUTH"`
+	GA4AuthOverride      AuthConfig `json:"GA4_AUTH" mapstructure:"GA4_AUTH"`
+	BigQueryAuthOverride AuthConfig `json:"BIGQUERY_AUTH" mapstructure:"BIGQUERY_AUTH"`
+}
+
+// AllConfig returns the effective configuration with secret values redacted.
+func (c Config) AllConfig() string {
+	return fmt.Sprintf("%+v", redact(reflect.ValueOf(c)).Interface())
+}
+
+func redact(v reflect.Value) reflect.Value {
+	out := reflect.New(v.Type()).Elem()
+	out.Set(v)
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		switch {
+		case f.Type.Kind() == reflect.Struct:
+			out.Field(i).Set(redact(v.Field(i)))
+		case f.Tag.Get("secret") == "true" && f.Type.Kind() == reflect.String && v.Field(i).String() != "":
+			out.Field(i).SetString(redacted)
+		case f.Tag.Get("secret") == "true" && f.Type.Kind() == reflect.Map && v.Field(i).Len() > 0:
+			m := reflect.MakeMap(f.Type)
+			for _, k := range v.Field(i).MapKeys() {
+				m.SetMapIndex(k, reflect.ValueOf(redacted))
+			}
+			out.Field(i).Set(m)
+		}
+	}
+	return out
+}
+
+// 출력 대상 (OUTPUT)
+const (
+	OutputBigQuery = "bigquery"
+	OutputCSV      = "csv"
+	OutputParquet  = "parquet"
+	OutputNdjson   = "ndjson"
+	OutputAvro     = "avro"
+	OutputPostgres = "postgres"
+	OutputSQLite   = "sqlite"
+	OutputDuckDB   = "duckdb"
+	OutputWebhook  = "webhook"
+)
+
+// Outputs lists every supported OUTPUT value.
+var Outputs = []string{OutputBigQuery, OutputCSV, OutputParquet, OutputNdjson, OutputAvro, OutputPostgres, OutputSQLite, OutputDuckDB, OutputWebhook}
+
+// fileOutputs write under OUT_DIR; streamOutputs additionally accept OUT_DIR "-" for stdout.
+var (
+	fileOutputs   = []string{OutputCSV, OutputParquet, OutputNdjson, OutputAvro}
+	streamOutputs = []string{OutputNdjson, OutputAvro}
+)
+
+// LogLevels and LogFormats list the supported LOG_LEVEL and LOG_FORMAT values.
+var (
+	LogLevels  = []string{"", "debug", "info", "warn", "error"}
+	LogFormats = []string{"", "text", "json"}
+)
+
+// Compressions lists every supported COMPRESSION value.
+// gzip is only for ndjson and deflate only for avro.
+var Compressions = []string{"", "gzip", "zstd", "deflate"}
+
+// Destinations returns OUTPUT, defaulting to BigQuery only.
+func (c *Config) Destinations() []string {
+	if len(c.Output) == 0 {
+		return []string{OutputBigQuery}
+	}
+	return c.Output
+}
+
+// OutputsFor returns the outputs of one report type, REPORT_OUTPUT first, then OUTPUT.
+func (c *Config) OutputsFor(reportType string) []string {
+	if outputs := c.ReportOutput[reportType]; len(outputs) > 0 {
+		return outputs
+	}
+	return c.Destinations()
+}
+
+// FullRefreshFor reports whether the report type replaces its BigQuery table on every run.
+func (c *Config) FullRefreshFor(reportType string) bool {
+	return contains(c.FullRefresh, reportType)
+}
+
+// HasOutput reports whether any configured report writes to name.
+func (c *Config) HasOutput(name string) bool {
+	if len(c.ReportTypes) == 0 {
+		return contains(c.Destinations(), name)
+	}
+	for _, r := range c.ReportTypes {
+		if contains(c.OutputsFor(r), name) {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookConfig 는 webhook 출력의 설정입니다.
+type WebhookConfig struct {
+	URL        string            `json:"URL" mapstructure:"URL"`
+	BatchSize  int               `json:"BATCH_SIZE" mapstructure:"BATCH_SIZE"`
+	Headers    map[string]string `json:"HEADERS" mapstructure:"HEADERS" secret:"true"`
+	HMACSecret string            `json:"HMAC_SECRET" mapstructure:"HMAC_SECRET" secret:"true"`
+	// MaxRetries 가 없으면 기본값 3 이고, 0 이면 재시도하지 않습니다.
+	MaxRetries *int `json:"MAX_RETRIES" mapstructure:"MAX_RETRIES"`
+}
+
+// CacheConfig controls the GA4 response cache. Responses whose last date is more than
+// STABLE_AFTER_DAYS old never expire; newer ones expire after TTL (e.g. "15m").
+type CacheConfig struct {
+	Dir             string        `json:"DIR" mapstructure:"DIR"`
+	TTL             time.Duration `json:"TTL" mapstructure:"TTL"`
+	StableAfterDays int           `json:"STABLE_AFTER_DAYS" mapstructure:"STABLE_AFTER_DAYS"`
+}
+
+// ReconcileConfig controls the post-load comparison of BigQuery sums with GA4 totals.
+// TOLERANCE is the allowed relative difference, e.g. 0.01 for 1%. METRICS lists the GA4
+// metrics to compare; by default only metrics that add up across rows are compared.
+type ReconcileConfig struct {
+	Enabled   bool     `json:"ENABLED" mapstructure:"ENABLED"`
+	Tolerance float64  `json:"TOLERANCE" mapstructure:"TOLERANCE"`
+	Metrics   []string `json:"METRICS" mapstructure:"METRICS"`
+}
+
+// Options 는 설정을 어디서 읽을지 결정합니다.
+type Options struct {
+	// File is an explicit config file. Its extension picks the format (json, yaml, toml).
+	File string
+	// Profile merges the named section under PROFILES over the base config.
+	Profile string
+	// Flags overrides config keys with the flags registered by BindFlags.
+	Flags *pflag.FlagSet
+}
+
+// flagKeys maps CLI flags registered by BindFlags to the config key they override.
+var flagKeys = map[string]string{
+	"property-id":    "PROPERTY_ID",
+	"project-id":     "PROJECT_ID",
+	"dataset-id":     "DATASET_ID",
+	"table-prefix":   "TABLE_PREFIX",
+	"location":       "DATASET_LOCATION",
+	"from":           "INITIAL_FETCH_FROM_DATE",
+	"to":             "FETCH_TO_DATE",
+	"report-types":   "REPORT_TYPES",
+	"output":         "OUTPUT",
+	"out-dir":        "OUT_DIR",
+	"compression":    "COMPRESSION",
+	"db":             "DB_PATH",
+	"archive-dir":    "ARCHIVE_DIR",
+	"cache-dir":      "CACHE.DIR",
+	"allow-breaking": "ALLOW_BREAKING",
+	"full-refresh":   "FULL_REFRESH",
+	"log-level":      "LOG_LEVEL",
+	"log-format":     "LOG_FORMAT",
+	"metrics-addr":   "METRICS_ADDR",
+}
+
+// flagAliases are alternative flag names; the alias wins when it is set.
+var flagAliases = map[string]string{
+	"sink": "output",
+}
+
+// BindFlags registers the config related flags on fs.
+func BindFlags(fs *pflag.FlagSet) {
+	fs.String("config", "", "config file, json/yaml/toml (default is ./.local.{json,yaml,toml})")
+	fs.String("profile", "", "named profile under PROFILES to apply (env "+EnvPrefix+"_PROFILE)")
+	fs.String("property-id", "", "override PROPERTY_ID")
+	fs.String("project-id", "", "override PROJECT_ID")
+	fs.String("dataset-id", "", "override DATASET_ID")
+	fs.String("table-prefix", "", "override TABLE_PREFIX")
+	fs.String("location", "", "override DATASET_LOCATION, e.g. asia-northeast3")
+	fs.String("from", "", "override INITIAL_FETCH_FROM_DATE")
+	fs.String("to", "", "override FETCH_TO_DATE")
+	fs.StringSlice("report-types", nil, "override REPORT_TYPES")
+	fs.StringSlice("output", nil, "override OUTPUT, comma separated: "+strings.Join(Outputs, ", ")+" (default bigquery)")
+	fs.String("out-dir", "", "override OUT_DIR, directory for file outputs (- for stdout with ndjson/avro)")
+	fs.String("compression", "", "override COMPRESSION: gzip (ndjson), deflate (avro) or zstd")
+	fs.String("db", "", "override DB_PATH, database file for sqlite/duckdb output")
+	fs.StringSlice("sink", nil, "alias of --output")
+	fs.String("archive-dir", "", "override ARCHIVE_DIR, where raw GA4 responses are kept for replay")
+	fs.String("cache-dir", "", "override CACHE.DIR, where GA4 responses are cached between runs")
+	fs.StringSlice("full-refresh", nil, "override FULL_REFRESH, report types whose BigQuery table is replaced through a staging table")
+	fs.String("log-level", "", "override LOG_LEVEL: debug, info, warn or error (default info)")
+	fs.String("log-format", "", "override LOG_FORMAT: text or json (default text)")
+	fs.String("metrics-addr", "", "override METRICS_ADDR, e.g. :9090, to serve Prometheus metrics on /metrics while running")
+	fs.Bool("allow-breaking", false, "override ALLOW_BREAKING: recreate BigQuery tables whose columns were dropped or retyped")
+}
+
+// OptionsFromFlags reads --config and --profile out of a flag set registered by BindFlags.
+func OptionsFromFlags(fs *pflag.FlagSet) Options {
+	opts := Options{Flags: fs}
+	if f := fs.Lookup("config"); f != nil {
+		opts.File = f.Value.String()
+	}
+	if f := fs.Lookup("profile"); f != nil {
+		opts.Profile = f.Value.String()
+	}
+	return opts
+}
+
+// New loads the config with precedence flag > GA4BQ_ env > profile > config file.
+// It returns the config and the file it was read from, which is empty when no file was found.
+func New(opts Options) (*Config, string, error) {
+	v := viper.New()
+
+	if opts.File != "" {
+		v.SetConfigFile(opts.File)
+	} else {
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, "", errors.Wrap(err, "failed to get working directory")
+		}
+		v.AddConfigPath(wd)
+		v.SetConfigName(".local")
+	}
+
+	if err := v.ReadInConfig(); err != nil {
+		var notFound viper.ConfigFileNotFoundError
+		if opts.File != "" || !errors.As(err, &notFound) {
+			return nil, "", errors.Wrap(err, "failed to read config")
+		}
+	}
+
+	v.SetEnvPrefix(EnvPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	for _, key := range Keys() {
+		if err := v.BindEnv(key); err != nil {
+			return nil, "", errors.Wrapf(err, "failed to bind env for %s", key)
+		}
+	}
+
+	profile := opts.Profile
+	if profile == "" {
+		profile = os.Getenv(EnvPrefix + "_PROFILE")
+	}
+	if profile != "" {
+		section := v.Sub("PROFILES." + profile)
+		if section == nil {
+			return nil, "", errors.Errorf("profile %q not found in config", profile)
+		}
+		if err := v.MergeConfigMap(section.AllSettings()); err != nil {
+			return nil, "", errors.Wrapf(err, "failed to apply profile %q", profile)
+		}
+	}
+
+	if opts.Flags != nil {
+		for name, key := range flagKeys {
+			if f := opts.Flags.Lookup(name); f != nil {
+				if err := v.BindPFlag(key, f); err != nil {
+					return nil, "", errors.Wrapf(err, "failed to bind flag --%s", name)
+				}
+			}
+		}
+		for alias, name := range flagAliases {
+			if f := opts.Flags.Lookup(alias); f != nil && f.Changed {
+				if err := v.BindPFlag(flagKeys[name], f); err != nil {
+					return nil, "", errors.Wrapf(err, "failed to bind flag --%s", alias)
+				}
+			}
+		}
+	}
+
+	cfg, err := Load(v)
+	if err != nil {
+		return nil, "", err
+	}
+	return cfg, v.ConfigFileUsed(), nil
+}
+
+// Load reads the typed configuration out of an already initialised viper instance.
+func Load(v *viper.Viper) (*Config, error) {
+	cfg := &Config{}
+	if err := v.Unmarshal(cfg); err != nil {
+		return nil, errors.Wrap(err, "failed to decode config")
+	}
+	return cfg, nil
+}
+
+// Keys returns every config key, nested keys joined with ".".
+func Keys() []string {
+	return keys(reflect.TypeOf(Config{}), "")
+}
+
+func keys(t reflect.Type, prefix string) []string {
+	var out []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := f.Tag.Get("mapstructure")
+		if name == "" || name == "-" {
+			continue
+		}
+		if f.Type.Kind() == reflect.Struct {
+			out = append(out, keys(f.Type, prefix+name+".")...)
+			continue
+		}
+		out = append(out, prefix+name)
+	}
+	return out
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+const testYAML = `
+PROPERTY_ID: "111"
+DATASET_ID: base_dataset
+SERVICE_ACCOUNT_FILE: /secret/sa.json
+REPORT_TYPES: [daily-events]
+PROFILES:
+  prod:
+    DATASET_ID: prod_dataset
+`
+
+func TestNew_Precedence(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(file, []byte(testYAML), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(EnvPrefix+"_PROJECT_ID", "env-project")
+
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	BindFlags(fs)
+	if err := fs.Parse([]string{"--config", file, "--profile", "prod", "--property-id", "222", "--location", "asia-northeast3"}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, used, err := New(OptionsFromFlags(fs))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if used != file {
+		t.Errorf("config file = %q, want %q", used, file)
+	}
+	if cfg.PropertyID != "222" {
+		t.Errorf("PropertyID = %q, want flag value 222", cfg.PropertyID)
+	}
+	if cfg.DatasetLocation != "asia-northeast3" {
+		t.Errorf("DatasetLocation = %q, want flag value asia-northeast3", cfg.DatasetLocation)
+	}
+	if cfg.DatasetID != "prod_dataset" {
+		t.Errorf("DatasetID = %q, want profile value prod_dataset", cfg.DatasetID)
+	}
+	if cfg.ProjectId != "env-project" {
+		t.Errorf("ProjectId = %q, want env value env-project", cfg.ProjectId)
+	}
+	if len(cfg.ReportTypes) != 1 || cfg.ReportTypes[0] != "daily-events" {
+		t.Errorf("ReportTypes = %v, want [daily-events]", cfg.ReportTypes)
+	}
+	if s := cfg.AllConfig(); strings.Contains(s, "/secret/sa.json") {
+		t.Errorf("AllConfig() leaks secret: %s", s)
+	}
+}
+
+func TestNew_UnknownProfile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(file, []byte(testYAML), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := New(Options{File: file, Profile: "staging"}); err == nil {
+		t.Fatal("New() error = nil, want unknown profile error")
+	}
+}
+
+func TestBindFlags_everyFlagIsBound(t *testing.T) {
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	BindFlags(fs)
+	fs.VisitAll(func(f *pflag.Flag) {
+		if _, ok := flagKeys[f.Name]; ok {
+			return
+		}
+		if _, ok := flagAliases[f.Name]; ok {
+			return
+		}
+		if f.Name != "config" && f.Name != "profile" {
+			t.Errorf("flag --%s is not bound to a config key", f.Name)
+		}
+	})
+}
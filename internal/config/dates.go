@@ -0,0 +1,31 @@
+package config
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ResolveDate turns a GA4 date (YYYY-MM-DD, today, yesterday or NdaysAgo) into
+// YYYY-MM-DD relative to now. GA4 resolves relative dates in the property time
+// zone, so pass now in that zone when it matters.
+func ResolveDate(value string, now time.Time) (string, error) {
+	switch {
+	case value == "today":
+		return now.Format(dateLayout), nil
+	case value == "yesterday":
+		return now.AddDate(0, 0, -1).Format(dateLayout), nil
+	case relativeDateRe.MatchString(value):
+		n, err := strconv.Atoi(strings.TrimSuffix(value, "daysAgo"))
+		if err != nil {
+			return "", errors.Wrapf(err, "invalid relative date %q", value)
+		}
+		return now.AddDate(0, 0, -n).Format(dateLayout), nil
+	}
+	if _, err := time.Parse(dateLayout, value); err != nil {
+		return "", errors.Wrapf(err, "invalid date %q", value)
+	}
+	return value, nil
+}
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestResolveDate(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: "today", want: "2024-03-01"},
+		{value: "yesterday", want: "2024-02-29"},
+		{value: "30daysAgo", want: "2024-01-31"},
+		{value: "2023-12-25", want: "2023-12-25"},
+		{value: "2023/12/25", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ResolveDate(tt.value, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveDate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ResolveDate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,281 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+var (
+	relativeDateRe = regexp.MustCompile(`^[0-9]+daysAgo$`)
+	propertyIDRe   = regexp.MustCompile(`^[0-9]+$`)
+	projectIDRe    = regexp.MustCompile(`^([a-z0-9.-]+:)?[a-z][a-z0-9-]{4,28}[a-z0-9]$`)
+	datasetIDRe    = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+	tablePrefixRe  = regexp.MustCompile(`^[A-Za-z0-9_]*$`)
+)
+
+// FieldError 는 하나의 설정 항목에 대한 검증 실패입니다.
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// ValidationErrors collects every problem found in a config so they can be reported at once.
+type ValidationErrors []FieldError
+
+func (v ValidationErrors) Error() string {
+	msgs := make([]string, 0, len(v))
+	for _, e := range v {
+		msgs = append(msgs, e.Error())
+	}
+	return fmt.Sprintf("%d config problem(s): %s", len(v), strings.Join(msgs, "; "))
+}
+
+func (v *ValidationErrors) add(field, format string, args ...interface{}) {
+	*v = append(*v, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// outputChecks are run by Validate for every configured output.
+var outputChecks []func(output string) error
+
+// RegisterOutputCheck adds a check that Validate runs for every configured output, e.g.
+// that the driver of an optional output is compiled into the binary.
+func RegisterOutputCheck(check func(output string) error) {
+	outputChecks = append(outputChecks, check)
+}
+
+// Validate checks every field of the config. knownReports lists the report types
+// the caller is able to run. A nil error means the config is usable.
+func (c *Config) Validate(knownReports []string) error {
+	var errs ValidationErrors
+
+	if len(c.ReportTypes) == 0 {
+		errs.add("REPORT_TYPES", "at least one report type is required")
+	}
+	for _, r := range c.ReportTypes {
+		if !contains(knownReports, r) {
+			errs.add("REPORT_TYPES", "unknown report type %q (supported: %s)", r, strings.Join(knownReports, ", "))
+		}
+	}
+
+	if c.PropertyID == "" {
+		errs.add("PROPERTY_ID", "is required")
+	} else if !propertyIDRe.MatchString(c.PropertyID) {
+		errs.add("PROPERTY_ID", "must be the numeric GA4 property id, got %q", c.PropertyID)
+	}
+
+	for _, o := range c.Destinations() {
+		if !contains(Outputs, o) {
+			errs.add("OUTPUT", "unknown output %q (supported: %s)", o, strings.Join(Outputs, ", "))
+		}
+	}
+	for r, outputs := range c.ReportOutput {
+		if !contains(knownReports, r) {
+			errs.add("REPORT_OUTPUT", "unknown report type %q", r)
+		}
+		for _, o := range outputs {
+			if !contains(Outputs, o) {
+				errs.add("REPORT_OUTPUT", "unknown output %q for %s (supported: %s)", o, r, strings.Join(Outputs, ", "))
+			}
+		}
+	}
+	for _, r := range c.FullRefresh {
+		if !contains(knownReports, r) {
+			errs.add("FULL_REFRESH", "unknown report type %q", r)
+		}
+	}
+	for _, o := range fileOutputs {
+		if !c.HasOutput(o) {
+			continue
+		}
+		if c.OutDir == "" {
+			errs.add("OUT_DIR", "is required for %s output", o)
+		} else if c.OutDir == "-" && !contains(streamOutputs, o) {
+			errs.add("OUT_DIR", "%s output cannot be written to stdout", o)
+		}
+	}
+	for _, o := range []string{OutputSQLite, OutputDuckDB} {
+		if c.HasOutput(o) && c.DBPath == "" {
+			errs.add("DB_PATH", "is required for %s output", o)
+		}
+	}
+	for _, o := range Outputs {
+		if !c.HasOutput(o) {
+			continue
+		}
+		for _, check := range outputChecks {
+			if err := check(o); err != nil {
+				errs.add("OUTPUT", "%v", err)
+			}
+		}
+	}
+	if c.HasOutput(OutputWebhook) {
+		if u, err := url.Parse(c.Webhook.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs.add("WEBHOOK.URL", "%q must be an http(s) URL", c.Webhook.URL)
+		}
+		if c.Webhook.BatchSize < 0 {
+			errs.add("WEBHOOK.BATCH_SIZE", "must not be negative")
+		}
+		if c.Webhook.MaxRetries != nil && *c.Webhook.MaxRetries < 0 {
+			errs.add("WEBHOOK.MAX_RETRIES", "must not be negative")
+		}
+	}
+	if c.HasOutput(OutputPostgres) && c.PostgresDSN == "" {
+		errs.add("POSTGRES_DSN", "is required for postgres output")
+	}
+	if c.DefaultTableExpiration < 0 {
+		errs.add("DEFAULT_TABLE_EXPIRATION", "must not be negative")
+	}
+	if c.Reconcile.Enabled && !c.HasOutput(OutputBigQuery) {
+		errs.add("RECONCILE.ENABLED", "needs bigquery in OUTPUT")
+	}
+	if c.Reconcile.Tolerance < 0 {
+		errs.add("RECONCILE.TOLERANCE", "must not be negative")
+	}
+	if c.Cache.TTL < 0 {
+		errs.add("CACHE.TTL", "must not be negative")
+	}
+	if c.Cache.StableAfterDays < 0 {
+		errs.add("CACHE.STABLE_AFTER_DAYS", "must not be negative")
+	}
+	if !contains(LogLevels, strings.ToLower(c.LogLevel)) {
+		errs.add("LOG_LEVEL", "unknown level %q (supported: debug, info, warn, error)", c.LogLevel)
+	}
+	if !contains(LogFormats, strings.ToLower(c.LogFormat)) {
+		errs.add("LOG_FORMAT", "unknown format %q (supported: text, json)", c.LogFormat)
+	}
+	if c.MetricsAddr != "" {
+		if _, _, err := net.SplitHostPort(c.MetricsAddr); err != nil {
+			errs.add("METRICS_ADDR", "%q must be host:port, e.g. :9090", c.MetricsAddr)
+		}
+	}
+	if !contains(Compressions, c.Compression) {
+		errs.add("COMPRESSION", "unknown compression %q (supported: gzip, zstd, deflate)", c.Compression)
+	}
+	if c.Compression == "gzip" && c.HasOutput(OutputAvro) {
+		errs.add("COMPRESSION", "avro has no gzip codec; use deflate or zstd")
+	}
+	if c.Compression == "deflate" && c.HasOutput(OutputNdjson) {
+		errs.add("COMPRESSION", "deflate is only supported by avro; use gzip or zstd for ndjson")
+	}
+
+	// PROJECT_ID 와 DATASET_ID 는 BigQuery 로 적재할 때만 필요합니다.
+	bigQuery := c.HasOutput(OutputBigQuery)
+	if c.ProjectId == "" {
+		if bigQuery {
+			errs.add("PROJECT_ID", "is required")
+		}
+	} else if !projectIDRe.MatchString(c.ProjectId) {
+		errs.add("PROJECT_ID", "%q is not a valid Google Cloud project id", c.ProjectId)
+	}
+
+	if c.DatasetID == "" {
+		if bigQuery {
+			errs.add("DATASET_ID", "is required")
+		}
+	} else if len(c.DatasetID) > 1024 || !datasetIDRe.MatchString(c.DatasetID) {
+		errs.add("DATASET_ID", "%q may only contain letters, numbers and underscores (max 1024)", c.DatasetID)
+	}
+
+	if !tablePrefixRe.MatchString(c.TablePrefix) {
+		errs.add("TABLE_PREFIX", "%q may only contain letters, numbers and underscores", c.TablePrefix)
+	}
+
+	from, fromOK := validateDate(&errs, "INITIAL_FETCH_FROM_DATE", c.InitialFetchFromDate)
+	to, toOK := validateDate(&errs, "FETCH_TO_DATE", c.FetchToDate)
+	if fromOK && toOK && !from.IsZero() && !to.IsZero() && from.After(to) {
+		errs.add("INITIAL_FETCH_FROM_DATE", "%s is after FETCH_TO_DATE %s", c.InitialFetchFromDate, c.FetchToDate)
+	}
+
+	if c.ServiceAccountFile != "" {
+		validateFile(&errs, "SERVICE_ACCOUNT_FILE", c.ServiceAccountFile)
+	}
+	if c.ClientSecretFile != "" {
+		validateFile(&errs, "CLIENT_SECRET_FILE", c.ClientSecretFile)
+	}
+	c.validateAuth(&errs, "GA4_AUTH", c.GA4Auth())
+	if bigQuery {
+		c.validateAuth(&errs, "BIGQUERY_AUTH", c.BigQueryAuth())
+	}
+
+	for _, s := range c.Scopes {
+		if !strings.HasPrefix(s, "https://www.googleapis.com/auth/") {
+			errs.add("SCOPES", "%q is not a Google OAuth scope", s)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (c *Config) validateAuth(errs *ValidationErrors, field string, a AuthConfig) {
+	switch a.Strategy {
+	case AuthServiceAccountFile:
+		if a.ServiceAccountFile == "" {
+			errs.add(field, "strategy %s needs SERVICE_ACCOUNT_FILE", a.Strategy)
+		} else if a.ServiceAccountFile != c.ServiceAccountFile {
+			validateFile(errs, field+".SERVICE_ACCOUNT_FILE", a.ServiceAccountFile)
+		}
+	case AuthADC:
+	case AuthImpersonate:
+		if a.TargetPrincipal == "" {
+			errs.add(field, "strategy %s needs TARGET_PRINCIPAL", a.Strategy)
+		}
+	case AuthOAuthToken:
+		if c.ClientSecretFile == "" {
+			errs.add(field, "strategy %s needs CLIENT_SECRET_FILE", a.Strategy)
+		}
+	default:
+		errs.add(field, "unknown STRATEGY %q (supported: %s)", a.Strategy, strings.Join(AuthStrategies, ", "))
+	}
+}
+
+// validateDate accepts YYYY-MM-DD and the GA4 relative forms today, yesterday and NdaysAgo.
+// The returned time is zero for relative dates.
+func validateDate(errs *ValidationErrors, field, value string) (time.Time, bool) {
+	switch {
+	case value == "":
+		errs.add(field, "is required")
+		return time.Time{}, false
+	case value == "today" || value == "yesterday" || relativeDateRe.MatchString(value):
+		return time.Time{}, true
+	}
+	t, err := time.Parse(dateLayout, value)
+	if err != nil {
+		errs.add(field, "%q must be YYYY-MM-DD, today, yesterday or NdaysAgo", value)
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+func validateFile(errs *ValidationErrors, field, path string) {
+	info, err := os.Stat(path)
+	if err != nil {
+		errs.add(field, "cannot read %q: %v", path, err)
+		return
+	}
+	if info.IsDir() {
+		errs.add(field, "%q is a directory", path)
+	}
+}
+
+func contains(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func validConfig(t *testing.T) *Config {
+	t.Helper()
+	sa := filepath.Join(t.TempDir(), "sa.json")
+	if err := os.WriteFile(sa, []byte("{}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return &Config{
+		ReportTypes:          []string{"daily-events"},
+		ServiceAccountFile:   sa,
+		Scopes:               []string{"https://www.googleapis.com/auth/analytics.readonly"},
+		PropertyID:           "123456",
+		InitialFetchFromDate: "2024-01-01",
+		FetchToDate:          "today",
+		ProjectId:            "ga4-project",
+		DatasetID:            "dataset_1",
+		TablePrefix:          "ga4_",
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name       string
+		mutate     func(c *Config)
+		wantFields []string
+	}{
+		{
+			name:   "valid config",
+			mutate: func(c *Config) {},
+		},
+		{
+			name:   "relative dates",
+			mutate: func(c *Config) { c.InitialFetchFromDate = "30daysAgo"; c.FetchToDate = "yesterday" },
+		},
+		{
+			name: "every problem is reported",
+			mutate: func(c *Config) {
+				c.PropertyID = ""
+				c.ReportTypes = []string{"daily-unknown"}
+				c.InitialFetchFromDate = "2024/01/01"
+				c.ServiceAccountFile = "/does/not/exist.json"
+				c.DatasetID = "bad-dataset"
+			},
+			wantFields: []string{"REPORT_TYPES", "PROPERTY_ID", "DATASET_ID", "INITIAL_FETCH_FROM_DATE", "SERVICE_ACCOUNT_FILE"},
+		},
+		{
+			name:       "unknown full refresh report",
+			mutate:     func(c *Config) { c.FullRefresh = []string{"daily-events", "daily-unknown"} },
+			wantFields: []string{"FULL_REFRESH"},
+		},
+		{
+			name:   "reconcile an appended report",
+			mutate: func(c *Config) { c.Reconcile.Enabled = true },
+		},
+		{
+			name:       "gzip avro",
+			mutate:     func(c *Config) { c.Output = []string{"avro"}; c.OutDir = "out"; c.Compression = "gzip" },
+			wantFields: []string{"COMPRESSION"},
+		},
+		{
+			name:   "deflate avro",
+			mutate: func(c *Config) { c.Output = []string{"avro"}; c.OutDir = "out"; c.Compression = "deflate" },
+		},
+		{
+			name:       "unknown log level and format",
+			mutate:     func(c *Config) { c.LogLevel = "verbose"; c.LogFormat = "xml" },
+			wantFields: []string{"LOG_LEVEL", "LOG_FORMAT"},
+		},
+		{
+			name:       "metrics address without port",
+			mutate:     func(c *Config) { c.MetricsAddr = "localhost" },
+			wantFields: []string{"METRICS_ADDR"},
+		},
+		{
+			name:       "from after to",
+			mutate:     func(c *Config) { c.InitialFetchFromDate = "2024-02-01"; c.FetchToDate = "2024-01-01" },
+			wantFields: []string{"INITIAL_FETCH_FROM_DATE"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := validConfig(t)
+			tt.mutate(c)
+			err := c.Validate([]string{"daily-events"})
+			if len(tt.wantFields) == 0 {
+				if err != nil {
+					t.Fatalf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			var verrs ValidationErrors
+			if !errors.As(err, &verrs) {
+				t.Fatalf("Validate() = %v, want ValidationErrors", err)
+			}
+			var got []string
+			for _, e := range verrs {
+				got = append(got, e.Field)
+			}
+			if len(got) != len(tt.wantFields) {
+				t.Fatalf("Validate() fields = %v, want %v", got, tt.wantFields)
+			}
+			for i := range got {
+				if got[i] != tt.wantFields[i] {
+					t.Errorf("Validate() fields = %v, want %v", got, tt.wantFields)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	ga "google.golang.org/api/analyticsdata/v1beta"
+
+	"go-ga4-to-bigquery/internal/config"
+	"go-ga4-to-bigquery/internal/reports"
+	"go-ga4-to-bigquery/pkg/ga4bq"
+)
+
+// Output formats for list-reports and describe-report.
+const (
+	FormatTable = "table"
+	FormatJSON  = "json"
+)
+
+// ReportInfo is one line of list-reports.
+type ReportInfo struct {
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	Version     string `json:"version,omitempty"`
+}
+
+// ReportDescription is everything describe-report knows about a report type.
+type ReportDescription struct {
+	Type            string               `json:"type"`
+	Description     string               `json:"description"`
+	Version         string               `json:"version,omitempty"`
+	Dimensions      []string             `json:"dimensions"`
+	Metrics         []string             `json:"metrics"`
+	DimensionFilter *ga.FilterExpression `json:"dimension_filter,omitempty"`
+	MetricFilter    *ga.FilterExpression `json:"metric_filter,omitempty"`
+	Schema          []ReportSchemaField  `json:"schema"`
+	NaturalKey      []string             `json:"natural_key"`
+	DefaultTable    string               `json:"default_table,omitempty"`
+	Table           string               `json:"table"`
+	Partitioning    []ReportPartitioning `json:"partitioning"`
+}
+
+// ReportPartitioning is how one configured output lays out the report's rows.
+type ReportPartitioning struct {
+	Output string `json:"output"`
+	Layout string `json:"layout"`
+}
+
+type ReportSchemaField struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Required bool   `json:"required"`
+}
+
+// ListReports returns every report type in r with its description.
+func ListReports(r *reports.Registry) []ReportInfo {
+	var out []ReportInfo
+	for _, name := range r.Names() {
+		meta, _ := r.Metadata(name)
+		out = append(out, ReportInfo{Type: name, Description: meta.Description, Version: meta.Version})
+	}
+	return out
+}
+
+// DescribeReport builds the description of reportType. The request is built with the
+// configured property and dates; cfg decides the table name and the outputs whose
+// layout is described.
+func DescribeReport(r *reports.Registry, reportType string, cfg *config.Config) (*ReportDescription, error) {
+	report, err := r.New(reportType)
+	if err != nil {
+		return nil, err
+	}
+	meta, _ := r.Metadata(reportType)
+	request := report.ReportRequestFunc(cfg.PropertyID, cfg.InitialFetchFromDate, cfg.FetchToDate)
+
+	d := &ReportDescription{
+		Type:            reportType,
+		Description:     meta.Description,
+		Version:         meta.Version,
+		DimensionFilter: request.DimensionFilter,
+		MetricFilter:    request.MetricFilter,
+		NaturalKey:      report.NaturalKey(),
+		DefaultTable:    meta.DefaultTable,
+		Table:           meta.TableName(cfg.TablePrefix, report, time.Now()),
+	}
+	for _, dim := range request.Dimensions {
+		d.Dimensions = append(d.Dimensions, dim.Name)
+	}
+	for _, m := range request.Metrics {
+		d.Metrics = append(d.Metrics, m.Name)
+	}
+	for _, f := range report.Schema() {
+		d.Schema = append(d.Schema, schemaField(f))
+	}
+	for _, output := range cfg.OutputsFor(reportType) {
+		d.Partitioning = append(d.Partitioning, ReportPartitioning{
+			Output: output,
+			Layout: partitionLayout(output, reportType, meta, report, cfg, d.Table),
+		})
+	}
+	return d, nil
+}
+
+// partitionLayout describes where output puts the rows of a run. table is the table
+// name of today's run.
+func partitionLayout(output, reportType string, meta reports.Metadata, report reports.Report, cfg *config.Config, table string) string {
+	keyed := meta.KeyedTableName(cfg.TablePrefix, report)
+	switch output {
+	case config.OutputBigQuery:
+		if meta.DefaultTable == "" {
+			return "table " + table + ", named by the report"
+		}
+		return "date-sharded tables " + keyed + "_YYYYMMDD, one per run date"
+	case config.OutputParquet:
+		return fmt.Sprintf("Hive partitions %s/property_id=%s/date=YYYY-MM-DD/%s.parquet, by the GA4 date of each row",
+			filepath.Join(cfg.OutDir, reportType), cfg.PropertyID, table)
+	case config.OutputCSV, config.OutputNdjson, config.OutputAvro:
+		return fmt.Sprintf("one file per run, %s.%s, not partitioned", filepath.Join(cfg.OutDir, table), output)
+	case config.OutputPostgres, config.OutputSQLite, config.OutputDuckDB:
+		return "one table " + keyed + ", upserted on the natural key, not partitioned"
+	case config.OutputWebhook:
+		return "not stored, rows are posted to WEBHOOK.URL"
+	default:
+		return "unknown"
+	}
+}
+
+func schemaField(f *bigquery.FieldSchema) ReportSchemaField {
+	return ReportSchemaField{Name: f.Name, Type: string(f.Type), Required: f.Required}
+}
+
+// ListReportsE prints the report types as a table or JSON.
+func (a *App) ListReportsE(cmd *cobra.Command, args []string) error {
+	format, _ := cmd.Flags().GetString("format")
+	list := ListReports(a.registry)
+	switch format {
+	case FormatJSON:
+		return writeJSON(cmd.OutOrStdout(), list)
+	case FormatTable:
+		tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "TYPE\tVERSION\tDESCRIPTION")
+		for _, r := range list {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", r.Type, r.Version, r.Description)
+		}
+		return tw.Flush()
+	default:
+		return errors.Errorf("unknown format %q (supported: table, json)", format)
+	}
+}
+
+// DescribeReportE prints one report's request, schema and destination. The config is
+// loaded without validation so the command works without credentials.
+func (a *App) DescribeReportE(cmd *cobra.Command, args []string) error {
+	if err := a.loadConfig(cmd); err != nil {
+		return &ga4bq.StageError{Stage: ga4bq.StageConfig, Err: err}
+	}
+	d, err := DescribeReport(a.registry, args[0], a.cfg)
+	if err != nil {
+		return &ga4bq.StageError{Stage: ga4bq.StageConfig, Err: err}
+	}
+
+	format, _ := cmd.Flags().GetString("format")
+	switch format {
+	case FormatJSON:
+		return writeJSON(cmd.OutOrStdout(), d)
+	case FormatTable:
+		return writeDescription(cmd.OutOrStdout(), d)
+	default:
+		return errors.Errorf("unknown format %q (supported: table, json)", format)
+	}
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func writeDescription(w io.Writer, d *ReportDescription) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	orNone := func(s string) string {
+		if s == "" {
+			return "(none)"
+		}
+		return s
+	}
+	fmt.Fprintf(tw, "Type:\t%s\n", d.Type)
+	fmt.Fprintf(tw, "Description:\t%s\n", d.Description)
+	fmt.Fprintf(tw, "Version:\t%s\n", orNone(d.Version))
+	fmt.Fprintf(tw, "Dimensions:\t%s\n", strings.Join(d.Dimensions, ", "))
+	fmt.Fprintf(tw, "Metrics:\t%s\n", strings.Join(d.Metrics, ", "))
+	for _, f := range []struct {
+		name string
+		expr *ga.FilterExpression
+	}{{"Dimension filter", d.DimensionFilter}, {"Metric filter", d.MetricFilter}} {
+		filter := ""
+		if f.expr != nil {
+			data, err := json.Marshal(f.expr)
+			if err != nil {
+				return errors.Wrap(err, "failed to encode filter")
+			}
+			filter = string(data)
+		}
+		fmt.Fprintf(tw, "%s:\t%s\n", f.name, orNone(filter))
+	}
+	fmt.Fprintf(tw, "Natural key:\t%s\n", strings.Join(d.NaturalKey, ", "))
+	fmt.Fprintf(tw, "Default table:\t%s\n", orNone(d.DefaultTable))
+	fmt.Fprintf(tw, "Table:\t%s\n", d.Table)
+	for _, part := range d.Partitioning {
+		fmt.Fprintf(tw, "Partitioning (%s):\t%s\n", part.Output, part.Layout)
+	}
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "COLUMN\tTYPE\tMODE")
+	for _, f := range d.Schema {
+		mode := "NULLABLE"
+		if f.Required {
+			mode = "REQUIRED"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", f.Name, f.Type, mode)
+	}
+	return tw.Flush()
+}
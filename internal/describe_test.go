@@ -0,0 +1,60 @@
+package internal
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"go-ga4-to-bigquery/internal/config"
+	"go-ga4-to-bigquery/internal/reports"
+)
+
+func TestDescribeReport(t *testing.T) {
+	cfg := &config.Config{PropertyID: "123", TablePrefix: "ga4_", OutDir: "out", Output: []string{config.OutputBigQuery, config.OutputParquet}}
+	tests := []struct {
+		name       string
+		reportType string
+		wantDims   []string
+		wantParts  []string
+		wantErr    bool
+	}{
+		{
+			name:       "events",
+			reportType: string(EVENTS),
+			wantDims:   []string{"eventName", "isConversionEvent", "date", "sessionDefaultChannelGroup"},
+			wantParts: []string{
+				"date-sharded tables ga4_daily_events_report_YYYYMMDD, one per run date",
+				"Hive partitions out/daily-events/property_id=123/date=YYYY-MM-DD/ga4_daily_events_report_",
+			},
+		},
+		{name: "unknown", reportType: "nope", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DescribeReport(reports.Default, tt.reportType, cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DescribeReport() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(got.Dimensions, tt.wantDims) {
+				t.Errorf("Dimensions = %v, want %v", got.Dimensions, tt.wantDims)
+			}
+			if got.Table[:4] != "ga4_" {
+				t.Errorf("destination = %s, want ga4_ prefix", got.Table)
+			}
+			if len(got.Partitioning) != len(tt.wantParts) {
+				t.Fatalf("Partitioning = %+v, want %d outputs", got.Partitioning, len(tt.wantParts))
+			}
+			for i, want := range tt.wantParts {
+				if !strings.HasPrefix(got.Partitioning[i].Layout, want) {
+					t.Errorf("Partitioning[%d] = %s, want prefix %s", i, got.Partitioning[i].Layout, want)
+				}
+			}
+			if len(got.Schema) == 0 || got.Description == "" {
+				t.Errorf("description or schema missing: %+v", got)
+			}
+		})
+	}
+}
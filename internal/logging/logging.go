@@ -0,0 +1,73 @@
+// Package logging sets up the structured slog logger shared by the CLI, the pipeline and the sinks.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// 로그 형식 (LOG_FORMAT)
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// ParseLevel parses LOG_LEVEL: debug, info, warn or error. Empty means info.
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if s == "" {
+		return slog.LevelInfo, nil
+	}
+	if err := level.UnmarshalText([]byte(strings.ToLower(s))); err != nil {
+		return level, errors.Errorf("unknown log level %q (supported: debug, info, warn, error)", s)
+	}
+	return level, nil
+}
+
+// New returns a logger writing to w in format (text by default) at level and above.
+func New(w io.Writer, format, level string) (*slog.Logger, error) {
+	lvl, err := ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+	switch strings.ToLower(format) {
+	case "", FormatText:
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case FormatJSON:
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	default:
+		return nil, errors.Errorf("unknown log format %q (supported: text, json)", format)
+	}
+}
+
+// Setup makes a logger built by New the slog default. The standard log package is
+// routed through it as well.
+func Setup(w io.Writer, format, level string) error {
+	logger, err := New(w, format, level)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logger)
+	return nil
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying logger, so that code further down, e.g. a
+// sink, logs with the caller's fields (run_id, property_id, report, chunk).
+func NewContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns the logger attached by NewContext, or the slog default.
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
@@ -0,0 +1,55 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name      string
+		format    string
+		level     string
+		wantDebug bool
+		wantStart string
+		wantErr   bool
+	}{
+		{name: "default text at info", wantStart: "time="},
+		{name: "json at debug", format: "json", level: "debug", wantDebug: true, wantStart: "{"},
+		{name: "upper case level", level: "WARN", wantStart: "time="},
+		{name: "unknown level", level: "verbose", wantErr: true},
+		{name: "unknown format", format: "xml", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger, err := New(&buf, tt.format, tt.level)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			logger.Debug("rows", "run_id", "r1")
+			if got := buf.Len() > 0; got != tt.wantDebug {
+				t.Errorf("debug logged = %v, want %v", got, tt.wantDebug)
+			}
+			buf.Reset()
+			logger.Error("failed", "run_id", "r1")
+			if !strings.HasPrefix(buf.String(), tt.wantStart) || !strings.Contains(buf.String(), "r1") {
+				t.Errorf("output = %q, want prefix %q and run_id", buf.String(), tt.wantStart)
+			}
+		})
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	var buf bytes.Buffer
+	logger, _ := New(&buf, "json", "info")
+	FromContext(NewContext(context.Background(), logger.With("report", "daily-events"))).Info("loaded")
+	if !strings.Contains(buf.String(), `"report":"daily-events"`) {
+		t.Errorf("output = %q, want the report field", buf.String())
+	}
+}
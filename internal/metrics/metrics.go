@@ -0,0 +1,138 @@
+// Package metrics holds the Prometheus collectors of the pipeline and serves them on /metrics.
+package metrics
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	ga "google.golang.org/api/analyticsdata/v1beta"
+	"google.golang.org/api/googleapi"
+)
+
+const namespace = "ga4bq"
+
+// 행 수를 세는 단계 (stage label)
+const (
+	StageFetched     = "fetched"
+	StageTransformed = "transformed"
+	StageLoaded      = "loaded"
+)
+
+// Registry holds every collector below plus the Go runtime and process collectors.
+var Registry = prometheus.NewRegistry()
+
+var (
+	GA4Requests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ga4_requests_total",
+		Help:      "GA4 Data API runReport requests by HTTP status, or \"error\" when there was no response.",
+	}, []string{"status"})
+
+	GA4Latency = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "ga4_request_duration_seconds",
+		Help:      "Latency of GA4 Data API runReport requests.",
+		Buckets:   []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+	})
+
+	Rows = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rows_total",
+		Help:      "Rows fetched from GA4, transformed and loaded into the sinks, per report.",
+	}, []string{"property_id", "report", "stage"})
+
+	BigQueryInsertErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "bigquery_insert_errors_total",
+		Help:      "Rows rejected by BigQuery streaming inserts, and failed full-refresh load jobs, per report.",
+	}, []string{"report"})
+
+	QuotaTokensRemaining = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "ga4_quota_tokens_remaining",
+		Help:      "GA4 property tokens remaining after the last request, per quota period.",
+	}, []string{"property_id", "period"})
+
+	LastSuccess = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "last_success_timestamp_seconds",
+		Help:      "Unix time of the last successful run of a report.",
+	}, []string{"property_id", "report"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		GA4Requests, GA4Latency, Rows, BigQueryInsertErrors, QuotaTokensRemaining, LastSuccess,
+	)
+}
+
+// Handler serves Registry in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// ObserveGA4Request records one runReport request that started at start and ended with err.
+func ObserveGA4Request(start time.Time, err error) {
+	GA4Latency.Observe(time.Since(start).Seconds())
+	status := strconv.Itoa(http.StatusOK)
+	if err != nil {
+		status = "error"
+		var apiErr *googleapi.Error
+		if errors.As(err, &apiErr) {
+			status = strconv.Itoa(apiErr.Code)
+		}
+	}
+	GA4Requests.WithLabelValues(status).Inc()
+}
+
+// ObserveQuota records the remaining tokens of a response requested with ReturnPropertyQuota.
+func ObserveQuota(propertyID string, quota *ga.PropertyQuota) {
+	if quota == nil {
+		return
+	}
+	for period, status := range map[string]*ga.QuotaStatus{"day": quota.TokensPerDay, "hour": quota.TokensPerHour} {
+		if status != nil {
+			QuotaTokensRemaining.WithLabelValues(propertyID, period).Set(float64(status.Remaining))
+		}
+	}
+}
+
+// ObserveInsertError counts the rows BigQuery rejected, or one for any other failure.
+func ObserveInsertError(reportType string, err error) {
+	n := 1
+	var multi bigquery.PutMultiError
+	if errors.As(err, &multi) && len(multi) > 0 {
+		n = len(multi)
+	}
+	BigQueryInsertErrors.WithLabelValues(reportType).Add(float64(n))
+}
+
+// Serve serves /metrics on addr until ctx is done. The listener is opened before Serve
+// returns, so a busy port is reported to the caller.
+func Serve(ctx context.Context, addr string) (net.Addr, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdown)
+	}()
+	go func() { _ = srv.Serve(ln) }()
+	return ln.Addr(), nil
+}
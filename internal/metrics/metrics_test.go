@@ -0,0 +1,91 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	ga "google.golang.org/api/analyticsdata/v1beta"
+	"google.golang.org/api/googleapi"
+)
+
+func TestObserveGA4Request(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status string
+	}{
+		{name: "ok", status: "200"},
+		{name: "quota exhausted", err: errors.Wrap(&googleapi.Error{Code: 429}, "failed"), status: "429"},
+		{name: "no response", err: errors.New("connection reset"), status: "error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := testutil.ToFloat64(GA4Requests.WithLabelValues(tt.status))
+			ObserveGA4Request(time.Now(), tt.err)
+			if got := testutil.ToFloat64(GA4Requests.WithLabelValues(tt.status)) - before; got != 1 {
+				t.Errorf("%s requests increased by %v, want 1", tt.status, got)
+			}
+		})
+	}
+}
+
+func TestObserveInsertError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want float64
+	}{
+		{name: "rejected rows", err: bigquery.PutMultiError{{RowIndex: 0}, {RowIndex: 3}}, want: 2},
+		{name: "other failure", err: errors.New("timeout"), want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := testutil.ToFloat64(BigQueryInsertErrors.WithLabelValues("daily-events"))
+			ObserveInsertError("daily-events", errors.Wrap(tt.err, "failed to insert data"))
+			if got := testutil.ToFloat64(BigQueryInsertErrors.WithLabelValues("daily-events")) - before; got != tt.want {
+				t.Errorf("insert errors increased by %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServe(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	addr, err := Serve(ctx, "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	Rows.WithLabelValues("123", "daily-events", StageLoaded).Add(2)
+	ObserveQuota("123", &ga.PropertyQuota{TokensPerDay: &ga.QuotaStatus{Remaining: 24000}})
+	LastSuccess.WithLabelValues("123", "daily-events").SetToCurrentTime()
+
+	resp, err := http.Get("http://" + addr.String() + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`ga4bq_rows_total{property_id="123",report="daily-events",stage="loaded"} 2`,
+		`ga4bq_ga4_quota_tokens_remaining{period="day",property_id="123"} 24000`,
+		`ga4bq_last_success_timestamp_seconds{property_id="123",report="daily-events"}`,
+		`ga4bq_ga4_request_duration_seconds_bucket`,
+		`go_goroutines`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("/metrics is missing %s", want)
+		}
+	}
+}
@@ -1,10 +1,7 @@
 package impl
 
 import (
-	"encoding/csv"
 	"fmt"
-	"log"
-	"os"
 	"strconv"
 	"time"
 
@@ -15,8 +12,15 @@ import (
 	"go-ga4-to-bigquery/internal/reports"
 )
 
-type ActiveUsersReport struct {
-	Items []reports.Item
+type ActiveUsersReport struct{}
+
+func init() {
+	reports.MustRegister(reports.Metadata{
+		Name:         "daily-active-users",
+		Description:  "Daily active, new, total and 1-day users and sessions by country, region and city",
+		Version:      "1",
+		DefaultTable: "daily_active_users",
+	}, func() reports.Report { return &ActiveUsersReport{} })
 }
 
 func (a ActiveUsersReport) ReportRequestFunc(propertyId, startDate, endDate string) *ga.RunReportRequest {
@@ -85,38 +89,6 @@ func (a ActiveUsersReportItem) Save() (row map[string]bigquery.Value, insertID s
 	return row, bigquery.NoDedupeID, nil
 }
 
-func (a ActiveUsersReportItem) Row() (row []string) {
-	var values []string
-	values = append(values, a.City)
-	return values
-}
-
-func (a ActiveUsersReport) CsvWriter(filePath string) error {
-	file, err := os.Create(filePath)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
-
-	// Writing the header
-	if err := writer.Write([]string{"Country", "Region", "City", "Date", "ActiveUsers", "Session", "total_users", "active_1day"}); err != nil {
-		return err
-	}
-
-	// Writing data
-	for _, item := range a.Items {
-		if err := writer.Write(item.Row()); err != nil {
-			return err
-		}
-	}
-
-	log.Printf("Data successfully saved to %s", filePath)
-	return nil
-}
-
 func (ActiveUsersReport) TransformFunc(result *ga.RunReportResponse) ([]bigquery.ValueSaver, error) {
 	var transformedData []bigquery.ValueSaver
 	for _, row := range result.Rows {
@@ -174,3 +146,22 @@ func (ActiveUsersReport) Schema() bigquery.Schema {
 		{Name: "active_1day_users", Required: true, Type: bigquery.IntegerFieldType},
 	}
 }
+
+func (ActiveUsersReport) NaturalKey() []string {
+	return []string{"country", "region", "city", "date"}
+}
+
+// ColumnSources maps each column to the GA4 dimension or metric it is read from.
+func (ActiveUsersReport) ColumnSources() map[string]string {
+	return map[string]string{
+		"country":           "country",
+		"region":            "region",
+		"city":              "city",
+		"date":              "date",
+		"active_users":      "activeUsers",
+		"new_users":         "newUsers",
+		"session":           "sessions",
+		"total_users":       "totalUsers",
+		"active_1day_users": "active1DayUsers",
+	}
+}
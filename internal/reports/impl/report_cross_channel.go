@@ -1,10 +1,7 @@
 package impl
 
 import (
-	"encoding/csv"
 	"fmt"
-	"log"
-	"os"
 	"strconv"
 	"time"
 
@@ -15,8 +12,15 @@ import (
 	"go-ga4-to-bigquery/internal/reports"
 )
 
-type CrossChannelReport struct {
-	Items []reports.Item
+type CrossChannelReport struct{}
+
+func init() {
+	reports.MustRegister(reports.Metadata{
+		Name:         "daily-cross-channel",
+		Description:  "Daily users and sessions by session campaign, channel group, medium and source",
+		Version:      "1",
+		DefaultTable: "daily_cross_channel",
+	}, func() reports.Report { return &CrossChannelReport{} })
 }
 
 func (a CrossChannelReport) ReportRequestFunc(propertyId, startDate, endDate string) *ga.RunReportRequest {
@@ -86,49 +90,6 @@ func (a CrossChannelReportItem) Save() (row map[string]bigquery.Value, insertID
 	return row, bigquery.NoDedupeID, nil
 }
 
-func (a CrossChannelReportItem) Row() (row []string) {
-	var values []string
-	values = append(values, a.SessionCampaignId)
-	return values
-}
-
-func (a CrossChannelReport) CsvWriter(filePath string) error {
-	file, err := os.Create(filePath)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
-
-	// Writing the header
-	if err := writer.Write([]string{
-		"SessionCampaignId",
-		"SessionCampaignName",
-		"SessionDefaultChannelGroup",
-		"SessionMedium",
-		"SessionSource",
-		"Date",
-		"ActiveUsers",
-		"NewUsers",
-		"Session",
-		"TotalUsers",
-	}); err != nil {
-		return err
-	}
-
-	// Writing data
-	for _, item := range a.Items {
-		if err := writer.Write(item.Row()); err != nil {
-			return err
-		}
-	}
-
-	log.Printf("Data successfully saved to %s", filePath)
-	return nil
-}
-
 func (CrossChannelReport) TransformFunc(result *ga.RunReportResponse) ([]bigquery.ValueSaver, error) {
 	var transformedData []bigquery.ValueSaver
 	for _, row := range result.Rows {
@@ -183,3 +144,23 @@ func (CrossChannelReport) Schema() bigquery.Schema {
 		{Name: "total_users", Required: true, Type: bigquery.IntegerFieldType},
 	}
 }
+
+func (CrossChannelReport) NaturalKey() []string {
+	return []string{"session_campaign_id", "session_campaign_name", "session_default_channel_group", "session_medium", "session_source", "date"}
+}
+
+// ColumnSources maps each column to the GA4 dimension or metric it is read from.
+func (CrossChannelReport) ColumnSources() map[string]string {
+	return map[string]string{
+		"session_campaign_id":           "sessionCampaignId",
+		"session_campaign_name":         "sessionCampaignName",
+		"session_default_channel_group": "sessionDefaultChannelGroup",
+		"session_medium":                "sessionMedium",
+		"session_source":                "sessionSource",
+		"date":                          "date",
+		"active_users":                  "activeUsers",
+		"new_users":                     "newUsers",
+		"session":                       "sessions",
+		"total_users":                   "totalUsers",
+	}
+}
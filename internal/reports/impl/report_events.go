@@ -1,9 +1,6 @@
 package impl
 
 import (
-	"encoding/csv"
-	"log"
-	"os"
 	"time"
 
 	"cloud.google.com/go/bigquery"
@@ -12,9 +9,17 @@ import (
 	"go-ga4-to-bigquery/internal/reports"
 )
 
-type EventsReport struct {
-	Items []reports.Item
+type EventsReport struct{}
+
+func init() {
+	reports.MustRegister(reports.Metadata{
+		Name:         "daily-events",
+		Description:  "Daily event counts by event name, conversion flag and session channel group",
+		Version:      "1",
+		DefaultTable: "daily_events_report",
+	}, func() reports.Report { return &EventsReport{} })
 }
+
 type EventReportItem struct {
 	EventName         string `json:"event_name"`           // ct_active_users
 	IsConversion      string `json:"is_conversion"`        // None
@@ -73,38 +78,6 @@ func (e EventReportItem) Save() (row map[string]bigquery.Value, insertID string,
 	return row, bigquery.NoDedupeID, nil
 }
 
-func (e EventReportItem) Row() (row []string) {
-	var values []string
-	values = append(values, e.EventName, e.IsConversion, e.EventDate, e.ChannelGroup, e.EventCount, e.EventCountPerUser, e.EventsPerSession, e.EventType)
-	return values
-}
-
-func (e EventsReport) CsvWriter(filePath string) error {
-	file, err := os.Create(filePath)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
-
-	// Writing the header
-	if err := writer.Write([]string{"EventName", "EventValue", "IsConversion", "EventDate", "ChannelGroup", "EventCount", "EventCountPerUser", "EventCountPerSession", "EventType"}); err != nil {
-		return err
-	}
-
-	// Writing data
-	for _, item := range e.Items {
-		if err := writer.Write(item.Row()); err != nil {
-			return err
-		}
-	}
-
-	log.Printf("Data successfully saved to %s", filePath)
-	return nil
-}
-
 /*
 {Name: "eventName"},                  // event_name
 			{Name: "isConversionEvent"},          // is_conversion
@@ -144,3 +117,20 @@ func (EventsReport) Schema() bigquery.Schema {
 		{Name: "event_type", Required: true, Type: bigquery.StringFieldType},
 	}
 }
+
+func (EventsReport) NaturalKey() []string {
+	return []string{"event_name", "is_conversion", "event_date", "channel_group"}
+}
+
+// ColumnSources maps each column to the GA4 dimension or metric it is read from.
+func (EventsReport) ColumnSources() map[string]string {
+	return map[string]string{
+		"event_name":           "eventName",
+		"is_conversion":        "isConversionEvent",
+		"event_date":           "date",
+		"channel_group":        "sessionDefaultChannelGroup",
+		"event_count":          "eventCount",
+		"event_count_per_user": "eventCountPerUser",
+		"events_per_session":   "eventsPerSession",
+	}
+}
@@ -1,10 +1,7 @@
 package impl
 
 import (
-	"encoding/csv"
 	"fmt"
-	"log"
-	"os"
 	"strconv"
 	"time"
 
@@ -15,8 +12,15 @@ import (
 	"go-ga4-to-bigquery/internal/reports"
 )
 
-type UserChannelGroupingReport struct {
-	Items []reports.Item
+type UserChannelGroupingReport struct{}
+
+func init() {
+	reports.MustRegister(reports.Metadata{
+		Name:         "daily-user-channel-grouping",
+		Description:  "Daily active users by default channel grouping",
+		Version:      "1",
+		DefaultTable: "user_channel_grouping",
+	}, func() reports.Report { return &UserChannelGroupingReport{} })
 }
 
 func (r UserChannelGroupingReport) ReportRequestFunc(propertyId, startDate, endDate string) *ga.RunReportRequest {
@@ -60,40 +64,6 @@ func (a UserChannelGroupingReportItem) Save() (row map[string]bigquery.Value, in
 	return row, bigquery.NoDedupeID, nil
 }
 
-func (a UserChannelGroupingReportItem) Row() (row []string) {
-	var values []string
-	values = append(values, a.DefaultChannelGrouping)
-	values = append(values, a.Date)
-	values = append(values, strconv.Itoa(a.ActiveUsers))
-	return values
-}
-
-func (a UserChannelGroupingReport) CsvWriter(filePath string) error {
-	file, err := os.Create(filePath)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
-
-	// Writing the header
-	if err := writer.Write([]string{"DefaultChannelGrouping", "Date", "ActiveUsers", "NewUsers"}); err != nil {
-		return err
-	}
-
-	// Writing data
-	for _, item := range a.Items {
-		if err := writer.Write(item.Row()); err != nil {
-			return err
-		}
-	}
-
-	log.Printf("Data successfully saved to %s", filePath)
-	return nil
-}
-
 func (UserChannelGroupingReport) TransformFunc(result *ga.RunReportResponse) ([]bigquery.ValueSaver, error) {
 	var transformedData []bigquery.ValueSaver
 	for _, row := range result.Rows {
@@ -120,3 +90,16 @@ func (UserChannelGroupingReport) Schema() bigquery.Schema {
 		{Name: "active_users", Required: true, Type: bigquery.IntegerFieldType},
 	}
 }
+
+func (UserChannelGroupingReport) NaturalKey() []string {
+	return []string{"default_channel_grouping", "date"}
+}
+
+// ColumnSources maps each column to the GA4 dimension or metric it is read from.
+func (UserChannelGroupingReport) ColumnSources() map[string]string {
+	return map[string]string{
+		"default_channel_grouping": "defaultChannelGrouping",
+		"date":                     "date",
+		"active_users":             "activeUsers",
+	}
+}
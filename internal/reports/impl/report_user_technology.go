@@ -1,10 +1,7 @@
 package impl
 
 import (
-	"encoding/csv"
 	"fmt"
-	"log"
-	"os"
 	"strconv"
 	"time"
 
@@ -17,8 +14,15 @@ import (
 
 // 브라우저 별 사용자 보고서
 
-type UserTechnologyReport struct {
-	Items []reports.Item
+type UserTechnologyReport struct{}
+
+func init() {
+	reports.MustRegister(reports.Metadata{
+		Name:         "daily-user-technology",
+		Description:  "Daily active users and sessions by browser, operating system, platform and device category",
+		Version:      "1",
+		DefaultTable: "user_technology",
+	}, func() reports.Report { return &UserTechnologyReport{} })
 }
 
 func (r UserTechnologyReport) ReportRequestFunc(propertyId, startDate, endDate string) *ga.RunReportRequest {
@@ -75,44 +79,6 @@ func (a UserTechnologyReportItem) Save() (row map[string]bigquery.Value, insertI
 	return row, bigquery.NoDedupeID, nil
 }
 
-func (a UserTechnologyReportItem) Row() (row []string) {
-	var values []string
-	values = append(values, a.Browser)
-	values = append(values, a.OperatingSystem)
-	values = append(values, a.Platform)
-	values = append(values, a.DeviceCategory)
-	values = append(values, a.Date)
-	values = append(values, strconv.Itoa(a.ActiveUsers))
-	values = append(values, strconv.Itoa(a.Session))
-	return values
-}
-
-func (a UserTechnologyReport) CsvWriter(filePath string) error {
-	file, err := os.Create(filePath)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
-
-	// Writing the header
-	if err := writer.Write([]string{"Country", "Region", "City", "Date", "ActiveUsers"}); err != nil {
-		return err
-	}
-
-	// Writing data
-	for _, item := range a.Items {
-		if err := writer.Write(item.Row()); err != nil {
-			return err
-		}
-	}
-
-	log.Printf("Data successfully saved to %s", filePath)
-	return nil
-}
-
 func (UserTechnologyReport) TransformFunc(result *ga.RunReportResponse) ([]bigquery.ValueSaver, error) {
 	var transformedData []bigquery.ValueSaver
 	for _, row := range result.Rows {
@@ -152,3 +118,20 @@ func (UserTechnologyReport) Schema() bigquery.Schema {
 		{Name: "session", Required: true, Type: bigquery.IntegerFieldType},
 	}
 }
+
+func (UserTechnologyReport) NaturalKey() []string {
+	return []string{"browser", "operating_system", "platform", "device_category", "date"}
+}
+
+// ColumnSources maps each column to the GA4 dimension or metric it is read from.
+func (UserTechnologyReport) ColumnSources() map[string]string {
+	return map[string]string{
+		"browser":          "browser",
+		"operating_system": "operatingSystem",
+		"platform":         "platform",
+		"device_category":  "deviceCategory",
+		"date":             "date",
+		"active_users":     "activeUsers",
+		"session":          "sessions",
+	}
+}
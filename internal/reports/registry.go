@@ -0,0 +1,124 @@
+package reports
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Metadata describes a registered report.
+type Metadata struct {
+	// Name 은 REPORT_TYPES 에 쓰는 리포트 타입입니다. (예: daily-events)
+	Name        string
+	Description string
+	Version     string
+	// DefaultTable is the table name without TABLE_PREFIX and the _YYYYMMDD date suffix.
+	DefaultTable string
+}
+
+// TableName returns the table the report loads for day: prefix, DefaultTable and day as
+// YYYYMMDD. Reports registered without DefaultTable keep prefix + ReportTitle().
+func (m Metadata) TableName(prefix string, report Report, day time.Time) string {
+	if m.DefaultTable == "" {
+		return prefix + report.ReportTitle()
+	}
+	return prefix + m.DefaultTable + "_" + day.Format("20060102")
+}
+
+// KeyedTableName is TableName without the date suffix, the one table that sinks upserting
+// on the natural key load into on every run.
+func (m Metadata) KeyedTableName(prefix string, report Report) string {
+	if m.DefaultTable == "" {
+		return prefix + report.ReportTitle()
+	}
+	return prefix + m.DefaultTable
+}
+
+// Factory returns a new instance of a report.
+type Factory func() Report
+
+type registration struct {
+	meta    Metadata
+	factory Factory
+}
+
+// Registry maps report types to their implementations. It is safe for concurrent use.
+type Registry struct {
+	mu      sync.RWMutex
+	entries map[string]registration
+}
+
+func NewRegistry() *Registry {
+	return &Registry{entries: map[string]registration{}}
+}
+
+// Register adds a report under meta.Name. Names must be unique.
+func (r *Registry) Register(meta Metadata, factory Factory) error {
+	if meta.Name == "" {
+		return errors.New("report name is required")
+	}
+	if factory == nil {
+		return errors.Errorf("report %s has no factory", meta.Name)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.entries[meta.Name]; ok {
+		return errors.Errorf("report %s is already registered", meta.Name)
+	}
+	r.entries[meta.Name] = registration{meta: meta, factory: factory}
+	return nil
+}
+
+// MustRegister is Register for init functions; it panics on error.
+func (r *Registry) MustRegister(meta Metadata, factory Factory) {
+	if err := r.Register(meta, factory); err != nil {
+		panic(err)
+	}
+}
+
+// New returns a new instance of the report registered as name.
+func (r *Registry) New(name string) (Report, error) {
+	r.mu.RLock()
+	e, ok := r.entries[name]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, errors.Errorf("unknown report type %q", name)
+	}
+	return e.factory(), nil
+}
+
+// Metadata returns the metadata of the report registered as name.
+func (r *Registry) Metadata(name string) (Metadata, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	e, ok := r.entries[name]
+	return e.meta, ok
+}
+
+// Names returns every registered report type, sorted.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.entries))
+	for name := range r.entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Default is the registry the built-in reports register into, and the one App uses
+// unless another is set. Programs embedding App can add their own reports to it.
+var Default = NewRegistry()
+
+// Register adds a report to Default.
+func Register(meta Metadata, factory Factory) error {
+	return Default.Register(meta, factory)
+}
+
+// MustRegister adds a report to Default and panics on error.
+func MustRegister(meta Metadata, factory Factory) {
+	Default.MustRegister(meta, factory)
+}
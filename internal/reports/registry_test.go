@@ -0,0 +1,96 @@
+package reports
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	ga "google.golang.org/api/analyticsdata/v1beta"
+)
+
+type fakeReport struct{}
+
+func (fakeReport) ReportRequestFunc(propertyId, startDate, endDate string) *ga.RunReportRequest {
+	return &ga.RunReportRequest{}
+}
+func (fakeReport) TransformFunc(*ga.RunReportResponse) ([]bigquery.ValueSaver, error) {
+	return nil, nil
+}
+func (fakeReport) Schema() bigquery.Schema { return nil }
+func (fakeReport) NaturalKey() []string    { return nil }
+func (fakeReport) ReportTitle() string     { return "fake" }
+
+func TestRegistry(t *testing.T) {
+	factory := func() Report { return fakeReport{} }
+	tests := []struct {
+		name    string
+		meta    Metadata
+		factory Factory
+		wantErr bool
+	}{
+		{name: "ok", meta: Metadata{Name: "b-report", Version: "1"}, factory: factory},
+		{name: "second", meta: Metadata{Name: "a-report"}, factory: factory},
+		{name: "duplicate", meta: Metadata{Name: "b-report"}, factory: factory, wantErr: true},
+		{name: "no name", meta: Metadata{}, factory: factory, wantErr: true},
+		{name: "no factory", meta: Metadata{Name: "c-report"}, wantErr: true},
+	}
+	r := NewRegistry()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := r.Register(tt.meta, tt.factory); (err != nil) != tt.wantErr {
+				t.Errorf("Register() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if got := r.Names(); !reflect.DeepEqual(got, []string{"a-report", "b-report"}) {
+		t.Errorf("Names() = %v", got)
+	}
+	if meta, ok := r.Metadata("b-report"); !ok || meta.Version != "1" {
+		t.Errorf("Metadata() = %+v, %v", meta, ok)
+	}
+	if _, err := r.New("b-report"); err != nil {
+		t.Errorf("New() error = %v", err)
+	}
+	if _, err := r.New("missing"); err == nil {
+		t.Error("New() of an unregistered report succeeded")
+	}
+}
+
+func TestMetadata_TableName(t *testing.T) {
+	day := time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		meta Metadata
+		want string
+	}{
+		{name: "default table", meta: Metadata{Name: "x", DefaultTable: "team_funnel"}, want: "ga4_team_funnel_20240305"},
+		{name: "no default table", meta: Metadata{Name: "x"}, want: "ga4_fake"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.meta.TableName("ga4_", fakeReport{}, day); got != tt.want {
+				t.Errorf("TableName() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMetadata_KeyedTableName(t *testing.T) {
+	tests := []struct {
+		name string
+		meta Metadata
+		want string
+	}{
+		{name: "default table", meta: Metadata{Name: "x", DefaultTable: "team_funnel"}, want: "ga4_team_funnel"},
+		{name: "no default table", meta: Metadata{Name: "x"}, want: "ga4_fake"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.meta.KeyedTableName("ga4_", fakeReport{}); got != tt.want {
+				t.Errorf("KeyedTableName() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -19,19 +19,21 @@ type Transformer interface {
 	TransformFunc(result *ga.RunReportResponse) ([]bigquery.ValueSaver, error)
 }
 
-type CsvWriter interface {
-	CsvWriter(filePath string) error
+// NaturalKeyer returns the columns that identify a row: the report's dimensions.
+type NaturalKeyer interface {
+	NaturalKey() []string
+}
+
+// ColumnSourcer maps schema columns to the GA4 dimension or metric API names they come
+// from, so that column descriptions can be taken from GA4 metadata. Optional.
+type ColumnSourcer interface {
+	ColumnSources() map[string]string
 }
 
 type Report interface {
 	ReportRequester
 	Transformer
 	SchemaGenerator
-	CsvWriter
+	NaturalKeyer
 	ReportTitle() string
 }
-
-type Item interface {
-	Save() (map[string]bigquery.Value, string, error)
-	Row() []string
-}
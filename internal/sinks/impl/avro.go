@@ -0,0 +1,136 @@
+package impl
+
+import (
+	"context"
+	"encoding/json"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/hamba/avro/v2/ocf"
+	"github.com/pkg/errors"
+
+	"go-ga4-to-bigquery/internal/logging"
+	"go-ga4-to-bigquery/internal/sinks"
+)
+
+// avroCodecs maps COMPRESSION to the OCF block codec; Avro compresses blocks itself
+// so the file stays readable by `bq load --source_format=AVRO`. Avro has no gzip codec.
+var avroCodecs = map[string]ocf.CodecName{
+	CompressionNone:    ocf.Null,
+	CompressionDeflate: ocf.Deflate,
+	CompressionZstd:    ocf.ZStandard,
+}
+
+// AvroSink writes each report as an Avro object container file to <dir>/<table>.avro or stdout.
+type AvroSink struct {
+	dir         string
+	compression string
+}
+
+func NewAvroSink(dir, compression string) *AvroSink {
+	return &AvroSink{dir: dir, compression: compression}
+}
+
+func (a *AvroSink) Name() string {
+	return "avro"
+}
+
+func (a *AvroSink) Prepare(ctx context.Context, dest sinks.Destination) (sinks.Batch, error) {
+	codec, ok := avroCodecs[a.compression]
+	if !ok {
+		return nil, errors.Errorf("unsupported compression %q", a.compression)
+	}
+	schema, err := AvroSchema(dest.Table, dest.Schema)
+	if err != nil {
+		return nil, err
+	}
+	out, err := openOutput(a.dir, dest.Table+".avro", CompressionNone)
+	if err != nil {
+		return nil, err
+	}
+	enc, err := ocf.NewEncoder(schema, out, ocf.WithCodec(codec))
+	if err != nil {
+		out.Abort()
+		return nil, errors.Wrap(err, "failed to create avro encoder")
+	}
+	return &avroBatch{out: out, enc: enc, schema: dest.Schema}, nil
+}
+
+// AvroSchema maps a BigQuery schema to an Avro record schema. Fields that are not
+// Required become ["null", type] unions.
+func AvroSchema(name string, schema bigquery.Schema) (string, error) {
+	fields := make([]map[string]interface{}, 0, len(schema))
+	for _, f := range schema {
+		var t interface{}
+		switch f.Type {
+		case bigquery.IntegerFieldType:
+			t = "long"
+		case bigquery.FloatFieldType:
+			t = "double"
+		case bigquery.StringFieldType:
+			t = "string"
+		case bigquery.BooleanFieldType:
+			t = "boolean"
+		case bigquery.DateFieldType:
+			t = map[string]string{"type": "int", "logicalType": "date"}
+		default:
+			return "", errors.Errorf("field %s: unsupported type %s", f.Name, f.Type)
+		}
+		field := map[string]interface{}{"name": f.Name, "type": t}
+		if !f.Required {
+			field["type"] = []interface{}{"null", t}
+			field["default"] = nil
+		}
+		fields = append(fields, field)
+	}
+	data, err := json.Marshal(map[string]interface{}{
+		"type":   "record",
+		"name":   name,
+		"fields": fields,
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to encode avro schema")
+	}
+	return string(data), nil
+}
+
+type avroBatch struct {
+	out    *outputFile
+	enc    *ocf.Encoder
+	schema bigquery.Schema
+}
+
+func (b *avroBatch) Write(ctx context.Context, rows []bigquery.ValueSaver) error {
+	for _, row := range rows {
+		values, _, err := row.Save()
+		if err != nil {
+			return errors.Wrap(err, "failed to save row")
+		}
+		typed, err := typedRow(b.schema, values)
+		if err != nil {
+			return err
+		}
+		record := make(map[string]interface{}, len(b.schema))
+		for i, f := range b.schema {
+			record[f.Name] = typed[i]
+		}
+		if err := b.enc.Encode(record); err != nil {
+			return errors.Wrap(err, "failed to write avro row")
+		}
+	}
+	return nil
+}
+
+func (b *avroBatch) Commit(ctx context.Context) error {
+	if err := b.enc.Close(); err != nil {
+		return errors.Wrap(err, "failed to flush avro file")
+	}
+	if err := b.out.Commit(); err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Info("Data successfully saved", "path", b.out.Path())
+	return nil
+}
+
+func (b *avroBatch) Abort(ctx context.Context) error {
+	return b.out.Abort()
+}
@@ -0,0 +1,47 @@
+package impl
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hamba/avro/v2/ocf"
+
+	"go-ga4-to-bigquery/internal/sinks"
+)
+
+func TestAvroSink(t *testing.T) {
+	dir := t.TempDir()
+	if err := sinks.Write(context.Background(), NewAvroSink(dir, CompressionZstd), testDestination, testRows); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	f, err := os.Open(filepath.Join(dir, "ga4_daily_test.avro"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	dec, err := ocf.NewDecoder(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []map[string]interface{}
+	for dec.HasNext() {
+		var row map[string]interface{}
+		if err := dec.Decode(&row); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, row)
+	}
+	if len(got) != 2 {
+		t.Fatalf("decoded %d rows, want 2", len(got))
+	}
+	// nullable fields decode as {"type": value} unions
+	if v := got[0]["active_users"].(map[string]interface{})["long"]; v != int64(12) {
+		t.Errorf("active_users = %v, want 12", v)
+	}
+	if v := got[1]["events_per_session"]; v != nil {
+		t.Errorf("events_per_session = %v, want nil", v)
+	}
+}
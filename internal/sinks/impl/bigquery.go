@@ -0,0 +1,231 @@
+package impl
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/pkg/errors"
+	"google.golang.org/api/googleapi"
+
+	"go-ga4-to-bigquery/internal/metrics"
+	"go-ga4-to-bigquery/internal/sinks"
+)
+
+// BigQuerySink streams report rows into one BigQuery table per report.
+type BigQuerySink struct {
+	client        *bigquery.Client
+	datasetID     string
+	allowBreaking bool
+	labels        map[string]string
+}
+
+func NewBigQuerySink(client *bigquery.Client, datasetID string) *BigQuerySink {
+	return &BigQuerySink{
+		client:    client,
+		datasetID: datasetID,
+	}
+}
+
+// WithAllowBreaking lets Prepare recreate an existing table whose schema cannot be
+// evolved in place, dropping its rows.
+func (b *BigQuerySink) WithAllowBreaking(allow bool) *BigQuerySink {
+	b.allowBreaking = allow
+	return b
+}
+
+// WithLabels adds labels to every table the sink creates, next to the property_id and
+// report_type labels it always sets.
+func (b *BigQuerySink) WithLabels(labels map[string]string) *BigQuerySink {
+	b.labels = labels
+	return b
+}
+
+// tableLabels returns the labels of dest's table.
+func (b *BigQuerySink) tableLabels(dest sinks.Destination) map[string]string {
+	labels := map[string]string{}
+	for k, v := range b.labels {
+		labels[k] = LabelValue(v)
+	}
+	labels["property_id"] = LabelValue(dest.PropertyID)
+	labels["report_type"] = LabelValue(dest.ReportType)
+	return labels
+}
+
+// LabelValue turns s into a valid BigQuery label value: lowercase letters, digits, "_"
+// and "-", at most 63 characters.
+func LabelValue(s string) string {
+	var out []rune
+	for _, r := range strings.ToLower(s) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_' || r == '-' {
+			out = append(out, r)
+		} else {
+			out = append(out, '_')
+		}
+		if len(out) == 63 {
+			break
+		}
+	}
+	return string(out)
+}
+
+func (b *BigQuerySink) Name() string {
+	return "bigquery"
+}
+
+// Prepare creates the destination table from the report schema, or reconciles the
+// schema of an existing table with it. Full-refresh destinations go through a staging table.
+func (b *BigQuerySink) Prepare(ctx context.Context, dest sinks.Destination) (sinks.Batch, error) {
+	if dest.FullRefresh {
+		return b.prepareRefresh(ctx, dest)
+	}
+	table := b.client.Dataset(b.datasetID).Table(dest.Table)
+	md, err := table.Metadata(ctx)
+	switch {
+	case err == nil:
+		if err := b.reconcileSchema(ctx, table, md, dest); err != nil {
+			return nil, err
+		}
+		return &bigQueryBatch{table: table, reportType: dest.ReportType}, nil
+	case !isNotFound(err):
+		return nil, errors.Wrap(err, "failed to read table metadata")
+	}
+
+	if err := table.Create(ctx, &bigquery.TableMetadata{
+		Schema: dest.Schema,
+		Labels: b.tableLabels(dest),
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to create table")
+	}
+	return &bigQueryBatch{table: table, reportType: dest.ReportType, created: true}, nil
+}
+
+// bigQueryBatch buffers rows so that an aborted fan-out never streams anything;
+// streaming inserts cannot be rolled back once sent.
+type bigQueryBatch struct {
+	table      *bigquery.Table
+	reportType string
+	created    bool
+	rows       []bigquery.ValueSaver
+}
+
+func (b *bigQueryBatch) Write(ctx context.Context, rows []bigquery.ValueSaver) error {
+	b.rows = append(b.rows, rows...)
+	return nil
+}
+
+func (b *bigQueryBatch) Commit(ctx context.Context) error {
+	if err := b.table.Inserter().Put(ctx, b.rows); err != nil {
+		metrics.ObserveInsertError(b.reportType, err)
+		return errors.Wrap(err, "failed to insert data")
+	}
+	return nil
+}
+
+// Abort drops the table if Prepare created it; an existing table keeps its rows.
+func (b *bigQueryBatch) Abort(ctx context.Context) error {
+	if !b.created {
+		return nil
+	}
+	if err := b.table.Delete(ctx); err != nil {
+		return errors.Wrap(err, "failed to delete table")
+	}
+	return nil
+}
+
+// Plan reports whether the destination table exists with the schema changes Prepare
+// would make to it, or otherwise the DDL Prepare would apply, and the steps of the load.
+// A full refresh plans the staging table's DDL as a query; streaming inserts run none.
+func (b *BigQuerySink) Plan(ctx context.Context, dest sinks.Destination) (sinks.Plan, error) {
+	table := b.client.Dataset(b.datasetID).Table(dest.Table)
+	plan := sinks.Plan{Output: b.Name(), Table: table.FullyQualifiedName()}
+	if md, err := table.Metadata(ctx); err == nil {
+		plan.Exists = true
+		for _, c := range DiffSchema(md.Schema, dest.Schema) {
+			plan.SchemaChanges = append(plan.SchemaChanges, c.String())
+		}
+	} else if !isNotFound(err) {
+		return plan, errors.Wrap(err, "failed to read table metadata")
+	} else {
+		plan.DDL = BigQueryDDL(b.client.Project(), b.datasetID, dest.Table, dest.Schema)
+	}
+
+	if !dest.FullRefresh {
+		plan.Steps = []string{"stream rows into " + dest.Table + " with insertAll"}
+		return plan, nil
+	}
+	staging := StagingTable(dest.Table, dest.RunID)
+	plan.Steps = []string{
+		"create staging table " + staging + ", expiring after " + stagingExpiration.String(),
+		"load rows into " + staging + " with a load job",
+		"check the staging row count against the rows written and the GA4 row count",
+		"copy " + staging + " over " + dest.Table + " with WRITE_TRUNCATE",
+		"label " + dest.Table + " and record any schema change",
+		"drop " + staging,
+	}
+	plan.Queries = []sinks.PlannedQuery{{SQL: BigQueryDDL(b.client.Project(), b.datasetID, staging, dest.Schema)}}
+	return plan, nil
+}
+
+// EstimateQueryBytes runs sql as a BigQuery dry-run query and returns the bytes it would process.
+func EstimateQueryBytes(ctx context.Context, client *bigquery.Client, sql string) (int64, error) {
+	q := client.Query(sql)
+	q.DryRun = true
+	job, err := q.Run(ctx)
+	if err != nil {
+		return 0, errors.Wrap(err, "dry-run query failed")
+	}
+	status := job.LastStatus()
+	if err := status.Err(); err != nil {
+		return 0, errors.Wrap(err, "dry-run query failed")
+	}
+	if status.Statistics == nil {
+		return 0, nil
+	}
+	return status.Statistics.TotalBytesProcessed, nil
+}
+
+// BigQueryDDL returns the CREATE TABLE statement equivalent to the table Prepare creates.
+func BigQueryDDL(project, dataset, table string, schema bigquery.Schema) string {
+	var cols []string
+	for _, f := range schema {
+		col := fmt.Sprintf("  `%s` %s", f.Name, bigQuerySQLType(f))
+		if f.Required {
+			col += " NOT NULL"
+		}
+		cols = append(cols, col)
+	}
+	return fmt.Sprintf("CREATE TABLE `%s.%s.%s` (\n%s\n)", project, dataset, table, strings.Join(cols, ",\n"))
+}
+
+// bigQuerySQLType maps the legacy field types of bigquery.Schema to GoogleSQL names.
+func bigQuerySQLType(f *bigquery.FieldSchema) string {
+	var t string
+	switch f.Type {
+	case bigquery.IntegerFieldType:
+		t = "INT64"
+	case bigquery.FloatFieldType:
+		t = "FLOAT64"
+	case bigquery.BooleanFieldType:
+		t = "BOOL"
+	case bigquery.RecordFieldType:
+		var fields []string
+		for _, sub := range f.Schema {
+			fields = append(fields, sub.Name+" "+bigQuerySQLType(sub))
+		}
+		t = "STRUCT<" + strings.Join(fields, ", ") + ">"
+	default:
+		t = string(f.Type)
+	}
+	if f.Repeated {
+		return "ARRAY<" + t + ">"
+	}
+	return t
+}
+
+func isNotFound(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound
+}
@@ -0,0 +1,164 @@
+package impl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/pkg/errors"
+
+	"go-ga4-to-bigquery/internal/logging"
+	"go-ga4-to-bigquery/internal/metrics"
+	"go-ga4-to-bigquery/internal/sinks"
+)
+
+// stagingExpiration 은 staging 테이블이 삭제되지 못했을 때를 대비한 만료 시간입니다.
+const stagingExpiration = 24 * time.Hour
+
+// StagingTable returns the staging table name of a full-refresh load of table.
+func StagingTable(table, runID string) string {
+	return table + "_staging_" + runID
+}
+
+// bigQueryRefreshBatch replaces a table atomically: rows are loaded into a staging
+// table with a load job, the row count is checked, and a WRITE_TRUNCATE copy swaps the
+// staging data into the target. Readers see either the old or the new rows, never a mix.
+type bigQueryRefreshBatch struct {
+	sink     *BigQuerySink
+	dest     sinks.Destination
+	target   *bigquery.Table
+	existing *bigquery.TableMetadata
+	rows     bytes.Buffer
+	count    int64
+}
+
+func (b *BigQuerySink) prepareRefresh(ctx context.Context, dest sinks.Destination) (sinks.Batch, error) {
+	if dest.RunID == "" {
+		return nil, errors.New("full refresh needs a run id")
+	}
+	target := b.client.Dataset(b.datasetID).Table(dest.Table)
+	batch := &bigQueryRefreshBatch{sink: b, dest: dest, target: target}
+
+	md, err := target.Metadata(ctx)
+	switch {
+	case err == nil:
+		// WRITE_TRUNCATE 복사는 스키마도 바꾸므로 파괴적인 변경은 여기서 막습니다.
+		if diff := DiffSchema(md.Schema, dest.Schema); diff.Breaking() && !b.allowBreaking {
+			return nil, errors.Errorf("table %s needs breaking schema changes (%s); rerun with --allow-breaking to replace it", dest.Table, diff)
+		}
+		batch.existing = md
+	case !isNotFound(err):
+		return nil, errors.Wrap(err, "failed to read table metadata")
+	}
+	return batch, nil
+}
+
+func (b *bigQueryRefreshBatch) Write(ctx context.Context, rows []bigquery.ValueSaver) error {
+	enc := json.NewEncoder(&b.rows)
+	for _, row := range rows {
+		values, _, err := row.Save()
+		if err != nil {
+			return errors.Wrap(err, "failed to save row")
+		}
+		if err := enc.Encode(values); err != nil {
+			return errors.Wrap(err, "failed to encode row")
+		}
+		b.count++
+	}
+	return nil
+}
+
+// Commit loads, validates and swaps. The staging table is dropped whatever happens, and
+// the target is only touched by the final copy job.
+func (b *bigQueryRefreshBatch) Commit(ctx context.Context) error {
+	dataset := b.sink.client.Dataset(b.sink.datasetID)
+	staging := dataset.Table(StagingTable(b.dest.Table, b.dest.RunID))
+	if err := staging.Create(ctx, &bigquery.TableMetadata{
+		Schema:         b.dest.Schema,
+		ExpirationTime: time.Now().Add(stagingExpiration),
+	}); err != nil {
+		return errors.Wrap(err, "failed to create staging table")
+	}
+	defer func() {
+		// 실패해도 만료 시간이 지나면 BigQuery 가 지웁니다.
+		if err := staging.Delete(context.Background()); err != nil && !isNotFound(err) {
+			logging.FromContext(ctx).Warn("Failed to drop staging table", "table", staging.TableID, "error", err)
+		}
+	}()
+
+	source := bigquery.NewReaderSource(bytes.NewReader(b.rows.Bytes()))
+	source.SourceFormat = bigquery.JSON
+	source.Schema = b.dest.Schema
+	loader := staging.LoaderFrom(source)
+	loader.WriteDisposition = bigquery.WriteTruncate
+	if err := runJob(ctx, loader); err != nil {
+		metrics.ObserveInsertError(b.dest.ReportType, err)
+		return errors.Wrap(err, "failed to load staging table")
+	}
+
+	md, err := staging.Metadata(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to read staging table")
+	}
+	if md.NumRows != uint64(b.count) {
+		return errors.Errorf("staging table has %d rows, wrote %d", md.NumRows, b.count)
+	}
+	if b.dest.ExpectedRows >= 0 && md.NumRows != uint64(b.dest.ExpectedRows) {
+		return errors.Errorf("staging table has %d rows, GA4 reported %d; %s left unchanged", md.NumRows, b.dest.ExpectedRows, b.dest.Table)
+	}
+
+	copier := b.target.CopierFrom(staging)
+	copier.WriteDisposition = bigquery.WriteTruncate
+	copier.CreateDisposition = bigquery.CreateIfNeeded
+	if err := runJob(ctx, copier); err != nil {
+		return errors.Wrap(err, "failed to swap staging table into place")
+	}
+	logging.FromContext(ctx).Info("Replaced table", "table", b.dest.Table, "rows", md.NumRows)
+
+	// 복사로 교체된 테이블의 label 과 스키마 버전을 다시 맞춥니다.
+	version, diff := 0, SchemaDiff(nil)
+	if b.existing != nil {
+		version = schemaVersion(b.existing.Labels)
+		if diff = DiffSchema(b.existing.Schema, b.dest.Schema); len(diff) > 0 {
+			version++
+		}
+	}
+	update := bigquery.TableMetadataToUpdate{}
+	for k, v := range b.sink.tableLabels(b.dest) {
+		update.SetLabel(k, v)
+	}
+	if version > 0 {
+		update.SetLabel(schemaVersionLabel, strconv.Itoa(version))
+	}
+	if _, err := b.target.Update(ctx, update, ""); err != nil {
+		return errors.Wrap(err, "failed to label table")
+	}
+	if len(diff) > 0 {
+		return b.sink.recordSchemaChange(ctx, b.dest.Table, version, diff)
+	}
+	return nil
+}
+
+// Abort has nothing to undo: nothing is created before Commit.
+func (b *bigQueryRefreshBatch) Abort(ctx context.Context) error {
+	return nil
+}
+
+type jobRunner interface {
+	Run(ctx context.Context) (*bigquery.Job, error)
+}
+
+func runJob(ctx context.Context, r jobRunner) error {
+	job, err := r.Run(ctx)
+	if err != nil {
+		return err
+	}
+	status, err := job.Wait(ctx)
+	if err != nil {
+		return err
+	}
+	return status.Err()
+}
@@ -0,0 +1,301 @@
+package impl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/pkg/errors"
+	"google.golang.org/api/googleapi"
+
+	"go-ga4-to-bigquery/internal/logging"
+	"go-ga4-to-bigquery/internal/sinks"
+)
+
+// SchemaChangesTable is the dataset table every schema change is recorded in.
+const SchemaChangesTable = "_schema_changes"
+
+// schemaVersionLabel 은 테이블 스키마 버전을 담는 label 입니다. 변경마다 1 씩 올라갑니다.
+const schemaVersionLabel = "ga4bq_schema_version"
+
+// Kinds of SchemaChange. Dropping a column or changing its type is breaking.
+const (
+	SchemaAddColumn   = "add_column"
+	SchemaRelaxColumn = "relax_column"
+	SchemaDropColumn  = "drop_column"
+	SchemaChangeType  = "change_type"
+)
+
+// SchemaChange is one difference between a live table and a report schema.
+type SchemaChange struct {
+	Kind   string `json:"kind"`
+	Column string `json:"column"`
+	From   string `json:"from,omitempty"`
+	To     string `json:"to,omitempty"`
+}
+
+func (c SchemaChange) Breaking() bool {
+	return c.Kind == SchemaDropColumn || c.Kind == SchemaChangeType
+}
+
+func (c SchemaChange) String() string {
+	switch c.Kind {
+	case SchemaAddColumn:
+		return fmt.Sprintf("add %s %s", c.Column, c.To)
+	case SchemaRelaxColumn:
+		return fmt.Sprintf("relax %s to NULLABLE", c.Column)
+	case SchemaDropColumn:
+		return fmt.Sprintf("drop %s %s", c.Column, c.From)
+	default:
+		return fmt.Sprintf("change %s from %s to %s", c.Column, c.From, c.To)
+	}
+}
+
+type SchemaDiff []SchemaChange
+
+// Breaking reports whether applying the diff loses data.
+func (d SchemaDiff) Breaking() bool {
+	for _, c := range d {
+		if c.Breaking() {
+			return true
+		}
+	}
+	return false
+}
+
+func (d SchemaDiff) String() string {
+	var parts []string
+	for _, c := range d {
+		parts = append(parts, c.String())
+	}
+	return strings.Join(parts, ", ")
+}
+
+// DiffSchema compares the top-level columns of a live table with the report schema.
+// A column the report requires but the table has as NULLABLE is not a change: BigQuery
+// cannot tighten a mode, and NULLABLE accepts every row the report produces.
+func DiffSchema(live, want bigquery.Schema) SchemaDiff {
+	var diff SchemaDiff
+	liveFields := map[string]*bigquery.FieldSchema{}
+	for _, f := range live {
+		liveFields[strings.ToLower(f.Name)] = f
+	}
+	wantFields := map[string]bool{}
+	for _, f := range want {
+		wantFields[strings.ToLower(f.Name)] = true
+		l, ok := liveFields[strings.ToLower(f.Name)]
+		switch {
+		case !ok:
+			diff = append(diff, SchemaChange{Kind: SchemaAddColumn, Column: f.Name, To: bigQuerySQLType(f)})
+		case bigQuerySQLType(l) != bigQuerySQLType(f):
+			diff = append(diff, SchemaChange{Kind: SchemaChangeType, Column: f.Name, From: bigQuerySQLType(l), To: bigQuerySQLType(f)})
+		case l.Required && !f.Required:
+			diff = append(diff, SchemaChange{Kind: SchemaRelaxColumn, Column: f.Name})
+		}
+	}
+	for _, f := range live {
+		if !wantFields[strings.ToLower(f.Name)] {
+			diff = append(diff, SchemaChange{Kind: SchemaDropColumn, Column: f.Name, From: bigQuerySQLType(f)})
+		}
+	}
+	return diff
+}
+
+// evolvedSchema applies the non-breaking changes of diff to live: added columns are
+// appended as NULLABLE, since BigQuery cannot add REQUIRED columns to a table with rows.
+// Column descriptions are taken from want where it has one. Names are matched
+// case-insensitively, as BigQuery does.
+func evolvedSchema(live bigquery.Schema, want bigquery.Schema, diff SchemaDiff) bigquery.Schema {
+	descriptions := map[string]string{}
+	for _, f := range want {
+		if f.Description != "" {
+			descriptions[strings.ToLower(f.Name)] = f.Description
+		}
+	}
+	relax := map[string]bool{}
+	add := map[string]bool{}
+	for _, c := range diff {
+		switch c.Kind {
+		case SchemaRelaxColumn:
+			relax[strings.ToLower(c.Column)] = true
+		case SchemaAddColumn:
+			add[strings.ToLower(c.Column)] = true
+		}
+	}
+	var out bigquery.Schema
+	for _, f := range live {
+		copied := *f
+		if relax[strings.ToLower(f.Name)] {
+			copied.Required = false
+		}
+		if d, ok := descriptions[strings.ToLower(f.Name)]; ok {
+			copied.Description = d
+		}
+		out = append(out, &copied)
+	}
+	for _, f := range want {
+		if add[strings.ToLower(f.Name)] {
+			copied := *f
+			copied.Required = false
+			out = append(out, &copied)
+		}
+	}
+	return out
+}
+
+// reconcileSchema brings an existing table in line with the report schema. New columns
+// are added and REQUIRED columns relaxed in place; dropped columns and type changes are
+// refused unless allowBreaking, in which case the table is replaced by an empty one.
+func (b *BigQuerySink) reconcileSchema(ctx context.Context, table *bigquery.Table, md *bigquery.TableMetadata, dest sinks.Destination) error {
+	want := dest.Schema
+	diff := DiffSchema(md.Schema, want)
+	if len(diff) == 0 {
+		// 설명만 바뀐 경우는 스키마 버전을 올리지 않습니다.
+		if !descriptionsChanged(md.Schema, want) {
+			return nil
+		}
+		update := bigquery.TableMetadataToUpdate{Schema: evolvedSchema(md.Schema, want, nil)}
+		if _, err := table.Update(ctx, update, md.ETag); err != nil {
+			return errors.Wrap(err, "failed to update column descriptions")
+		}
+		return nil
+	}
+	if diff.Breaking() && !b.allowBreaking {
+		return errors.Errorf("table %s needs breaking schema changes (%s); rerun with --allow-breaking to recreate it", table.TableID, diff)
+	}
+
+	version := schemaVersion(md.Labels) + 1
+	if diff.Breaking() {
+		if err := b.replaceTable(ctx, table, dest, version); err != nil {
+			return err
+		}
+	} else {
+		update := bigquery.TableMetadataToUpdate{Schema: evolvedSchema(md.Schema, want, diff)}
+		update.SetLabel(schemaVersionLabel, strconv.Itoa(version))
+		if _, err := table.Update(ctx, update, md.ETag); err != nil {
+			return errors.Wrap(err, "failed to update table schema")
+		}
+	}
+
+	logging.FromContext(ctx).Info("Schema changed", "table", table.TableID, "version", version, "changes", diff.String())
+	return b.recordSchemaChange(ctx, table.TableID, version, diff)
+}
+
+// replaceTable swaps table for an empty one with dest's schema. The replacement is built
+// as a staging table first and copied over table with WRITE_TRUNCATE, as a full refresh
+// does, so table is never missing if a step fails.
+func (b *BigQuerySink) replaceTable(ctx context.Context, table *bigquery.Table, dest sinks.Destination, version int) error {
+	runID := dest.RunID
+	if runID == "" {
+		runID = strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	staging := b.client.Dataset(b.datasetID).Table(StagingTable(table.TableID, runID))
+	if err := staging.Create(ctx, &bigquery.TableMetadata{
+		Schema:         dest.Schema,
+		ExpirationTime: time.Now().Add(stagingExpiration),
+	}); err != nil {
+		return errors.Wrap(err, "failed to create replacement table")
+	}
+	defer func() {
+		if err := staging.Delete(context.Background()); err != nil && !isNotFound(err) {
+			logging.FromContext(ctx).Warn("Failed to drop staging table", "table", staging.TableID, "error", err)
+		}
+	}()
+
+	copier := table.CopierFrom(staging)
+	copier.WriteDisposition = bigquery.WriteTruncate
+	if err := runJob(ctx, copier); err != nil {
+		return errors.Wrap(err, "failed to replace table for breaking schema change")
+	}
+	update := bigquery.TableMetadataToUpdate{}
+	for k, v := range b.versionedLabels(dest, version) {
+		update.SetLabel(k, v)
+	}
+	if _, err := table.Update(ctx, update, ""); err != nil {
+		return errors.Wrap(err, "failed to label table")
+	}
+	return nil
+}
+
+// descriptionsChanged reports whether want describes a column differently from live,
+// matching column names case-insensitively.
+func descriptionsChanged(live, want bigquery.Schema) bool {
+	described := map[string]string{}
+	for _, f := range live {
+		described[strings.ToLower(f.Name)] = f.Description
+	}
+	for _, f := range want {
+		if f.Description != "" && described[strings.ToLower(f.Name)] != f.Description {
+			return true
+		}
+	}
+	return false
+}
+
+// versionedLabels returns the labels of dest's table with the schema version set.
+func (b *BigQuerySink) versionedLabels(dest sinks.Destination, version int) map[string]string {
+	labels := b.tableLabels(dest)
+	labels[schemaVersionLabel] = strconv.Itoa(version)
+	return labels
+}
+
+func schemaVersion(labels map[string]string) int {
+	v, err := strconv.Atoi(labels[schemaVersionLabel])
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+// schemaChangeRecord is a row of SchemaChangesTable.
+type schemaChangeRecord struct {
+	table     string
+	version   int
+	changedAt time.Time
+	breaking  bool
+	changes   string
+}
+
+var schemaChangesSchema = bigquery.Schema{
+	{Name: "table_name", Type: bigquery.StringFieldType, Required: true},
+	{Name: "version", Type: bigquery.IntegerFieldType, Required: true},
+	{Name: "changed_at", Type: bigquery.TimestampFieldType, Required: true},
+	{Name: "breaking", Type: bigquery.BooleanFieldType, Required: true},
+	{Name: "changes", Type: bigquery.StringFieldType, Required: true},
+}
+
+func (r schemaChangeRecord) Save() (map[string]bigquery.Value, string, error) {
+	return map[string]bigquery.Value{
+		"table_name": r.table,
+		"version":    r.version,
+		"changed_at": r.changedAt,
+		"breaking":   r.breaking,
+		"changes":    r.changes,
+	}, fmt.Sprintf("%s-%d", r.table, r.version), nil
+}
+
+func (b *BigQuerySink) recordSchemaChange(ctx context.Context, tableID string, version int, diff SchemaDiff) error {
+	changes, err := json.Marshal(diff)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode schema changes")
+	}
+	changeLog := b.client.Dataset(b.datasetID).Table(SchemaChangesTable)
+	if err := changeLog.Create(ctx, &bigquery.TableMetadata{Schema: schemaChangesSchema}); err != nil && !isAlreadyExists(err) {
+		return errors.Wrap(err, "failed to create schema change log")
+	}
+	record := schemaChangeRecord{table: tableID, version: version, changedAt: time.Now(), breaking: diff.Breaking(), changes: string(changes)}
+	if err := changeLog.Inserter().Put(ctx, record); err != nil {
+		return errors.Wrap(err, "failed to record schema change")
+	}
+	return nil
+}
+
+func isAlreadyExists(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusConflict
+}
@@ -0,0 +1,137 @@
+package impl
+
+import (
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+)
+
+func TestBigQueryDDL(t *testing.T) {
+	schema := bigquery.Schema{
+		{Name: "date", Type: bigquery.StringFieldType, Required: true},
+		{Name: "active_users", Type: bigquery.IntegerFieldType, Required: true},
+		{Name: "ratio", Type: bigquery.FloatFieldType},
+		{Name: "tags", Type: bigquery.StringFieldType, Repeated: true},
+	}
+	want := "CREATE TABLE `proj.ds.daily` (\n" +
+		"  `date` STRING NOT NULL,\n" +
+		"  `active_users` INT64 NOT NULL,\n" +
+		"  `ratio` FLOAT64,\n" +
+		"  `tags` ARRAY<STRING>\n" +
+		")"
+	if got := BigQueryDDL("proj", "ds", "daily", schema); got != want {
+		t.Errorf("BigQueryDDL() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestDiffSchema(t *testing.T) {
+	live := bigquery.Schema{
+		{Name: "date", Type: bigquery.StringFieldType, Required: true},
+		{Name: "active_users", Type: bigquery.IntegerFieldType, Required: true},
+	}
+	tests := []struct {
+		name         string
+		want         bigquery.Schema
+		wantDiff     string
+		wantBreaking bool
+	}{
+		{name: "same", want: live},
+		{
+			name:     "added column",
+			want:     append(live[:2:2], &bigquery.FieldSchema{Name: "new_users", Type: bigquery.IntegerFieldType, Required: true}),
+			wantDiff: "add new_users INT64",
+		},
+		{
+			name:     "relaxed column",
+			want:     bigquery.Schema{live[0], {Name: "active_users", Type: bigquery.IntegerFieldType}},
+			wantDiff: "relax active_users to NULLABLE",
+		},
+		{
+			name:         "type change",
+			want:         bigquery.Schema{live[0], {Name: "active_users", Type: bigquery.FloatFieldType, Required: true}},
+			wantDiff:     "change active_users from INT64 to FLOAT64",
+			wantBreaking: true,
+		},
+		{
+			name:         "dropped column",
+			want:         live[:1],
+			wantDiff:     "drop active_users INT64",
+			wantBreaking: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := DiffSchema(live, tt.want)
+			if got := diff.String(); got != tt.wantDiff {
+				t.Errorf("DiffSchema() = %q, want %q", got, tt.wantDiff)
+			}
+			if diff.Breaking() != tt.wantBreaking {
+				t.Errorf("Breaking() = %v, want %v", diff.Breaking(), tt.wantBreaking)
+			}
+		})
+	}
+}
+
+func TestEvolvedSchema(t *testing.T) {
+	live := bigquery.Schema{{Name: "date", Type: bigquery.StringFieldType, Required: true}}
+	want := bigquery.Schema{
+		{Name: "date", Type: bigquery.StringFieldType, Required: true},
+		{Name: "sessions", Type: bigquery.IntegerFieldType, Required: true},
+	}
+	got := evolvedSchema(live, want, DiffSchema(live, want))
+	if len(got) != 2 || got[1].Name != "sessions" || got[1].Required {
+		t.Errorf("evolvedSchema() = %v, want sessions appended as NULLABLE", got)
+	}
+	if !want[1].Required {
+		t.Error("evolvedSchema() modified the report schema")
+	}
+}
+
+func TestEvolvedSchema_caseInsensitive(t *testing.T) {
+	live := bigquery.Schema{{Name: "Date", Type: bigquery.StringFieldType, Required: true}}
+	want := bigquery.Schema{{Name: "date", Type: bigquery.StringFieldType, Description: "Date: The date of the event"}}
+	got := evolvedSchema(live, want, DiffSchema(live, want))
+	if len(got) != 1 || got[0].Required || got[0].Description == "" {
+		t.Errorf("evolvedSchema() = %+v, want Date relaxed and described", got[0])
+	}
+}
+
+func TestDescriptionsChanged(t *testing.T) {
+	live := bigquery.Schema{{Name: "Date", Type: bigquery.StringFieldType, Description: "Date: The date of the event"}}
+	tests := []struct {
+		name   string
+		schema bigquery.Schema
+		want   bool
+	}{
+		{name: "same description, other case", schema: bigquery.Schema{{Name: "date", Type: bigquery.StringFieldType, Description: "Date: The date of the event"}}},
+		{name: "no description", schema: bigquery.Schema{{Name: "date", Type: bigquery.StringFieldType}}},
+		{name: "new description", schema: bigquery.Schema{{Name: "date", Type: bigquery.StringFieldType, Description: "Date"}}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := descriptionsChanged(live, tt.schema); got != tt.want {
+				t.Errorf("descriptionsChanged() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLabelValue(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "daily-events", want: "daily-events"},
+		{in: "1.0.0", want: "1_0_0"},
+		{in: "Asia Northeast3", want: "asia_northeast3"},
+		{in: strings.Repeat("a", 70), want: strings.Repeat("a", 63)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := LabelValue(tt.in); got != tt.want {
+				t.Errorf("LabelValue() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
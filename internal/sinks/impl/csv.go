@@ -0,0 +1,126 @@
+package impl
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/pkg/errors"
+
+	"go-ga4-to-bigquery/internal/atomicfile"
+	"go-ga4-to-bigquery/internal/logging"
+	"go-ga4-to-bigquery/internal/sinks"
+)
+
+// CsvSink writes each report to <dir>/<table>.csv. The header and column order come
+// from the report schema and every value from the row's Save() map.
+type CsvSink struct {
+	dir string
+}
+
+func NewCsvSink(dir string) *CsvSink {
+	return &CsvSink{dir: dir}
+}
+
+func (c *CsvSink) Name() string {
+	return "csv"
+}
+
+// Prepare writes into a temporary file that Commit renames into place.
+func (c *CsvSink) Prepare(ctx context.Context, dest sinks.Destination) (sinks.Batch, error) {
+	file, err := atomicfile.Create(filepath.Join(c.dir, dest.Table+".csv"))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create csv file")
+	}
+	enc, err := newCsvEncoder(file, dest.Schema)
+	if err != nil {
+		file.Abort()
+		return nil, err
+	}
+	return &csvBatch{file: file, enc: enc}, nil
+}
+
+type csvBatch struct {
+	file *atomicfile.File
+	enc  *csvEncoder
+}
+
+func (b *csvBatch) Write(ctx context.Context, rows []bigquery.ValueSaver) error {
+	return b.enc.Encode(rows)
+}
+
+func (b *csvBatch) Commit(ctx context.Context) error {
+	if err := b.enc.Flush(); err != nil {
+		return err
+	}
+	if err := b.file.Commit(); err != nil {
+		return errors.Wrap(err, "failed to save csv file")
+	}
+	logging.FromContext(ctx).Info("Data successfully saved", "path", b.file.Path())
+	return nil
+}
+
+func (b *csvBatch) Abort(ctx context.Context) error {
+	return errors.Wrap(b.file.Abort(), "failed to remove csv file")
+}
+
+type csvEncoder struct {
+	w      *csv.Writer
+	schema bigquery.Schema
+	record []string
+}
+
+func newCsvEncoder(w io.Writer, schema bigquery.Schema) (*csvEncoder, error) {
+	enc := &csvEncoder{w: csv.NewWriter(w), schema: schema, record: make([]string, len(schema))}
+	for i, field := range schema {
+		enc.record[i] = field.Name
+	}
+	if err := enc.w.Write(enc.record); err != nil {
+		return nil, errors.Wrap(err, "failed to write csv header")
+	}
+	return enc, nil
+}
+
+func (e *csvEncoder) Encode(rows []bigquery.ValueSaver) error {
+	for _, row := range rows {
+		values, _, err := row.Save()
+		if err != nil {
+			return errors.Wrap(err, "failed to save row")
+		}
+		for i, field := range e.schema {
+			e.record[i] = formatCsvValue(values[field.Name])
+		}
+		if err := e.w.Write(e.record); err != nil {
+			return errors.Wrap(err, "failed to write csv row")
+		}
+	}
+	return nil
+}
+
+func (e *csvEncoder) Flush() error {
+	e.w.Flush()
+	return errors.Wrap(e.w.Error(), "failed to flush csv")
+}
+
+func formatCsvValue(v bigquery.Value) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case int:
+		return strconv.Itoa(t)
+	case int64:
+		return strconv.FormatInt(t, 10)
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(t)
+	default:
+		return fmt.Sprint(t)
+	}
+}
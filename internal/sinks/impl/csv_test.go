@@ -0,0 +1,67 @@
+package impl
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+
+	"go-ga4-to-bigquery/internal/sinks"
+)
+
+type testRow map[string]bigquery.Value
+
+func (r testRow) Save() (map[string]bigquery.Value, string, error) {
+	return r, bigquery.NoDedupeID, nil
+}
+
+var testDestination = sinks.Destination{
+	PropertyID: "123",
+	ReportType: "daily-test",
+	Table:      "ga4_daily_test",
+	Schema: bigquery.Schema{
+		{Name: "date", Type: bigquery.StringFieldType},
+		{Name: "active_users", Type: bigquery.IntegerFieldType},
+		{Name: "events_per_session", Type: bigquery.FloatFieldType},
+	},
+	StartDate: "2024-01-01",
+	EndDate:   "2024-01-02",
+}
+
+var testRows = []bigquery.ValueSaver{
+	testRow{"active_users": 12, "date": "20240101", "events_per_session": 1.5},
+	testRow{"date": "20240102", "active_users": 3},
+}
+
+func TestCsvSink(t *testing.T) {
+	dir := t.TempDir()
+	if err := sinks.Write(context.Background(), NewCsvSink(dir), testDestination, testRows); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(dir, "ga4_daily_test.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "date,active_users,events_per_session\n20240101,12,1.5\n20240102,3,\n"
+	if string(got) != want {
+		t.Errorf("csv = %q, want %q", got, want)
+	}
+}
+
+func TestCsvSink_Abort(t *testing.T) {
+	dir := t.TempDir()
+	batch, err := NewCsvSink(dir).Prepare(context.Background(), testDestination)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := batch.Abort(context.Background()); err != nil {
+		t.Fatalf("Abort() error = %v", err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Errorf("Abort() left %d file(s) behind", len(entries))
+	}
+}
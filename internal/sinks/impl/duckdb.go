@@ -0,0 +1,21 @@
+//go:build duckdb
+
+package impl
+
+import (
+	"cloud.google.com/go/bigquery"
+	_ "github.com/marcboeker/go-duckdb"
+)
+
+func init() {
+	localDialects["duckdb"] = localDialect{
+		driver: "duckdb",
+		types: map[bigquery.FieldType]string{
+			bigquery.IntegerFieldType: "BIGINT",
+			bigquery.FloatFieldType:   "DOUBLE",
+			bigquery.StringFieldType:  "VARCHAR",
+			bigquery.DateFieldType:    "DATE",
+			bigquery.BooleanFieldType: "BOOLEAN",
+		},
+	}
+}
@@ -0,0 +1,207 @@
+package impl
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/pkg/errors"
+
+	"go-ga4-to-bigquery/internal/config"
+	"go-ga4-to-bigquery/internal/logging"
+	"go-ga4-to-bigquery/internal/sinks"
+)
+
+// localDialect describes an embedded database driver usable by LocalDBSink.
+type localDialect struct {
+	driver string
+	types  map[bigquery.FieldType]string
+}
+
+// localDialects is filled by the files registering a driver. DuckDB needs cgo and is
+// only compiled in with `-tags duckdb`.
+var localDialects = map[string]localDialect{}
+
+// localOutputs are the OUTPUT values served by LocalDBSink.
+var localOutputs = []string{config.OutputSQLite, config.OutputDuckDB}
+
+func init() {
+	config.RegisterOutputCheck(checkLocalDriver)
+}
+
+// checkLocalDriver fails for a local database output whose driver is not compiled in.
+func checkLocalDriver(output string) error {
+	for _, o := range localOutputs {
+		if o != output {
+			continue
+		}
+		if _, ok := localDialects[output]; !ok {
+			return errNotCompiledIn(output)
+		}
+	}
+	return nil
+}
+
+func errNotCompiledIn(name string) error {
+	return errors.Errorf("%s support is not compiled in, rebuild with -tags %s", name, name)
+}
+
+// LocalDBSink loads reports into an embedded SQLite or DuckDB file for offline
+// inspection. Tables are created from the report schema, named without the run-date
+// suffix, and rows are upserted on the natural key, so the same SQL as in BigQuery can
+// run against a local copy and re-runs on later days update the same rows.
+type LocalDBSink struct {
+	name    string
+	db      *sql.DB
+	dialect localDialect
+}
+
+func NewLocalDBSink(name, path string) (*LocalDBSink, error) {
+	dialect, ok := localDialects[name]
+	if !ok {
+		return nil, errNotCompiledIn(name)
+	}
+	db, err := sql.Open(dialect.driver, path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open %s database", name)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, errors.Wrapf(err, "failed to open %s database %s", name, path)
+	}
+	return &LocalDBSink{name: name, db: db, dialect: dialect}, nil
+}
+
+func (l *LocalDBSink) Name() string {
+	return l.name
+}
+
+func (l *LocalDBSink) Close() error {
+	return l.db.Close()
+}
+
+// CreateTableDDL returns the CREATE TABLE statement for dest in this dialect.
+func (l *LocalDBSink) CreateTableDDL(dest sinks.Destination) (string, error) {
+	cols := make([]string, 0, len(dest.Schema)+1)
+	for _, f := range dest.Schema {
+		t, ok := l.dialect.types[f.Type]
+		if !ok {
+			return "", errors.Errorf("field %s: unsupported type %s", f.Name, f.Type)
+		}
+		col := quoteIdent(f.Name) + " " + t
+		if f.Required {
+			col += " NOT NULL"
+		}
+		cols = append(cols, col)
+	}
+	if len(dest.Key) > 0 {
+		cols = append(cols, "PRIMARY KEY ("+quoteIdents(dest.Key)+")")
+	}
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n\t%s\n)", quoteIdent(dest.Table), strings.Join(cols, ",\n\t")), nil
+}
+
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func quoteIdents(names []string) string {
+	quoted := make([]string, len(names))
+	for i, n := range names {
+		quoted[i] = quoteIdent(n)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func (l *LocalDBSink) Prepare(ctx context.Context, dest sinks.Destination) (sinks.Batch, error) {
+	dest = dest.Keyed()
+	ddl, err := l.CreateTableDDL(dest)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := l.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to begin transaction")
+	}
+	if _, err := tx.ExecContext(ctx, ddl); err != nil {
+		tx.Rollback()
+		return nil, errors.Wrap(err, "failed to create table")
+	}
+	stmt, err := tx.PrepareContext(ctx, insertSQL(dest))
+	if err != nil {
+		tx.Rollback()
+		return nil, errors.Wrap(err, "failed to prepare insert")
+	}
+	return &localDBBatch{name: l.name, tx: tx, stmt: stmt, dest: dest}, nil
+}
+
+// insertSQL upserts on the natural key; both SQLite and DuckDB accept ON CONFLICT.
+func insertSQL(dest sinks.Destination) string {
+	cols := make([]string, len(dest.Schema))
+	params := make([]string, len(dest.Schema))
+	isKey := make(map[string]bool, len(dest.Key))
+	for _, k := range dest.Key {
+		isKey[k] = true
+	}
+	var updates []string
+	for i, f := range dest.Schema {
+		cols[i] = f.Name
+		params[i] = "?"
+		if !isKey[f.Name] {
+			updates = append(updates, quoteIdent(f.Name)+" = excluded."+quoteIdent(f.Name))
+		}
+	}
+	stmt := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", quoteIdent(dest.Table), quoteIdents(cols), strings.Join(params, ", "))
+	if len(dest.Key) == 0 {
+		return stmt
+	}
+	stmt += " ON CONFLICT (" + quoteIdents(dest.Key) + ")"
+	if len(updates) == 0 {
+		return stmt + " DO NOTHING"
+	}
+	return stmt + " DO UPDATE SET " + strings.Join(updates, ", ")
+}
+
+type localDBBatch struct {
+	name  string
+	tx    *sql.Tx
+	stmt  *sql.Stmt
+	dest  sinks.Destination
+	count int
+}
+
+func (b *localDBBatch) Write(ctx context.Context, rows []bigquery.ValueSaver) error {
+	for _, row := range rows {
+		values, _, err := row.Save()
+		if err != nil {
+			return errors.Wrap(err, "failed to save row")
+		}
+		typed, err := typedRow(b.dest.Schema, values)
+		if err != nil {
+			return err
+		}
+		if _, err := b.stmt.ExecContext(ctx, typed...); err != nil {
+			return errors.Wrap(err, "failed to insert row")
+		}
+		b.count++
+	}
+	return nil
+}
+
+func (b *localDBBatch) Commit(ctx context.Context) error {
+	b.stmt.Close()
+	if err := b.tx.Commit(); err != nil {
+		return errors.Wrap(err, "failed to commit transaction")
+	}
+	logging.FromContext(ctx).Info("Loaded rows", "rows", b.count, "output", b.name, "table", b.dest.Table)
+	return nil
+}
+
+func (b *localDBBatch) Abort(ctx context.Context) error {
+	b.stmt.Close()
+	if err := b.tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+		return errors.Wrap(err, "failed to roll back transaction")
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package impl
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+
+	"go-ga4-to-bigquery/internal/sinks"
+)
+
+// TestLocalDBSink runs for every compiled-in dialect; add -tags duckdb to cover DuckDB.
+func TestLocalDBSink(t *testing.T) {
+	for name := range localDialects {
+		t.Run(name, func(t *testing.T) {
+			testLocalDBSink(t, name)
+		})
+	}
+}
+
+func testLocalDBSink(t *testing.T, name string) {
+	ctx := context.Background()
+	sink, err := NewLocalDBSink(name, filepath.Join(t.TempDir(), "ga4."+name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	dest := testDestination
+	dest.Key = []string{"date"}
+	dest.Table, dest.KeyedTable = "ga4_daily_test_20240102", "ga4_daily_test"
+	if err := sinks.Write(ctx, sink, dest, testRows); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	// 다음 날 다시 실행해도 같은 테이블에 upsert 됩니다.
+	dest.Table = "ga4_daily_test_20240103"
+	updated := []bigquery.ValueSaver{testRow{"date": "20240101", "active_users": 20}}
+	if err := sinks.Write(ctx, sink, dest, updated); err != nil {
+		t.Fatalf("Write() upsert error = %v", err)
+	}
+
+	var count, activeUsers int
+	if err := sink.db.QueryRow(`SELECT count(*), max("active_users") FROM "ga4_daily_test"`).Scan(&count, &activeUsers); err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 || activeUsers != 20 {
+		t.Errorf("got %d rows with max active_users %d, want 2 rows and 20", count, activeUsers)
+	}
+}
+
+func TestCheckLocalDriver(t *testing.T) {
+	_, duckdb := localDialects["duckdb"]
+	tests := []struct {
+		output  string
+		wantErr bool
+	}{
+		{output: "sqlite", wantErr: false},
+		{output: "duckdb", wantErr: !duckdb},
+		{output: "bigquery", wantErr: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.output, func(t *testing.T) {
+			if err := checkLocalDriver(tt.output); (err != nil) != tt.wantErr {
+				t.Errorf("checkLocalDriver(%s) error = %v, wantErr %v", tt.output, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package impl
+
+import (
+	"context"
+	"encoding/json"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/pkg/errors"
+
+	"go-ga4-to-bigquery/internal/logging"
+	"go-ga4-to-bigquery/internal/sinks"
+)
+
+// NdjsonSink writes one JSON object per row, exactly the map returned by Save(),
+// to <dir>/<table>.ndjson or stdout. The files load with `bq load --source_format=NEWLINE_DELIMITED_JSON`.
+type NdjsonSink struct {
+	dir         string
+	compression string
+}
+
+func NewNdjsonSink(dir, compression string) *NdjsonSink {
+	return &NdjsonSink{dir: dir, compression: compression}
+}
+
+func (n *NdjsonSink) Name() string {
+	return "ndjson"
+}
+
+func (n *NdjsonSink) Prepare(ctx context.Context, dest sinks.Destination) (sinks.Batch, error) {
+	out, err := openOutput(n.dir, dest.Table+".ndjson", n.compression)
+	if err != nil {
+		return nil, err
+	}
+	return &ndjsonBatch{out: out, enc: json.NewEncoder(out)}, nil
+}
+
+type ndjsonBatch struct {
+	out *outputFile
+	enc *json.Encoder
+}
+
+func (b *ndjsonBatch) Write(ctx context.Context, rows []bigquery.ValueSaver) error {
+	for _, row := range rows {
+		values, _, err := row.Save()
+		if err != nil {
+			return errors.Wrap(err, "failed to save row")
+		}
+		if err := b.enc.Encode(values); err != nil {
+			return errors.Wrap(err, "failed to write json row")
+		}
+	}
+	return nil
+}
+
+func (b *ndjsonBatch) Commit(ctx context.Context) error {
+	if err := b.out.Commit(); err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Info("Data successfully saved", "path", b.out.Path())
+	return nil
+}
+
+func (b *ndjsonBatch) Abort(ctx context.Context) error {
+	return b.out.Abort()
+}
@@ -0,0 +1,38 @@
+package impl
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go-ga4-to-bigquery/internal/sinks"
+)
+
+func TestNdjsonSink_Gzip(t *testing.T) {
+	dir := t.TempDir()
+	if err := sinks.Write(context.Background(), NewNdjsonSink(dir, CompressionGzip), testDestination, testRows); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	f, err := os.Open(filepath.Join(dir, "ga4_daily_test.ndjson.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"active_users":12,"date":"20240101","events_per_session":1.5}` + "\n" +
+		`{"active_users":3,"date":"20240102"}` + "\n"
+	if string(got) != want {
+		t.Errorf("ndjson = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,106 @@
+package impl
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+
+	"go-ga4-to-bigquery/internal/atomicfile"
+)
+
+// Stdout is the OUT_DIR value that sends file outputs to standard output.
+const Stdout = "-"
+
+// 압축 방식 (COMPRESSION)
+const (
+	CompressionNone = ""
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+	// CompressionDeflate is the Avro deflate block codec; it is not a stream compression.
+	CompressionDeflate = "deflate"
+)
+
+// compressionExt is appended to file names of stream-compressed outputs.
+var compressionExt = map[string]string{
+	CompressionNone: "",
+	CompressionGzip: ".gz",
+	CompressionZstd: ".zst",
+}
+
+// outputFile is the target of a file sink: a temporary file renamed into place on
+// Commit, or stdout. Writes go through the optional compression stream.
+type outputFile struct {
+	io.Writer
+	file       *atomicfile.File
+	compressor io.WriteCloser
+}
+
+// openOutput opens <dir>/<name><ext> (or stdout when dir is Stdout) with the given
+// stream compression. Pass CompressionNone for formats that compress internally.
+func openOutput(dir, name, compression string) (*outputFile, error) {
+	ext, ok := compressionExt[compression]
+	if !ok {
+		return nil, errors.Errorf("unsupported compression %q", compression)
+	}
+
+	out := &outputFile{}
+	if dir == Stdout {
+		out.Writer = os.Stdout
+	} else {
+		file, err := atomicfile.Create(filepath.Join(dir, name+ext))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create output file")
+		}
+		out.file = file
+		out.Writer = file
+	}
+
+	switch compression {
+	case CompressionGzip:
+		out.compressor = gzip.NewWriter(out.Writer)
+	case CompressionZstd:
+		zw, err := zstd.NewWriter(out.Writer)
+		if err != nil {
+			out.Abort()
+			return nil, errors.Wrap(err, "failed to create zstd writer")
+		}
+		out.compressor = zw
+	}
+	if out.compressor != nil {
+		out.Writer = out.compressor
+	}
+	return out, nil
+}
+
+// Commit flushes the compression stream and moves the file into place.
+func (o *outputFile) Commit() error {
+	if o.compressor != nil {
+		if err := o.compressor.Close(); err != nil {
+			return errors.Wrap(err, "failed to close compression stream")
+		}
+	}
+	if o.file == nil {
+		return nil
+	}
+	return errors.Wrap(o.file.Commit(), "failed to save output file")
+}
+
+// Abort removes the temporary file. Whatever already went to stdout stays there.
+func (o *outputFile) Abort() error {
+	if o.file == nil {
+		return nil
+	}
+	return errors.Wrap(o.file.Abort(), "failed to remove output file")
+}
+
+// Path returns the final file path, or "stdout".
+func (o *outputFile) Path() string {
+	if o.file == nil {
+		return "stdout"
+	}
+	return o.file.Path()
+}
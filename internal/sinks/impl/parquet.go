@@ -0,0 +1,239 @@
+package impl
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/apache/arrow/go/v15/arrow"
+	"github.com/apache/arrow/go/v15/arrow/array"
+	"github.com/apache/arrow/go/v15/arrow/memory"
+	"github.com/apache/arrow/go/v15/parquet"
+	"github.com/apache/arrow/go/v15/parquet/compress"
+	"github.com/apache/arrow/go/v15/parquet/pqarrow"
+	"github.com/pkg/errors"
+
+	"go-ga4-to-bigquery/internal/atomicfile"
+	"go-ga4-to-bigquery/internal/config"
+	"go-ga4-to-bigquery/internal/logging"
+	"go-ga4-to-bigquery/internal/sinks"
+)
+
+// dateFields are the schema fields holding the GA4 `date` dimension, used to split
+// rows into date=YYYY-MM-DD partitions.
+var dateFields = []string{"date", "event_date"}
+
+// ParquetSink writes each report as Parquet files under Hive-style partitions:
+// <dir>/<report type>/property_id=<id>/date=<YYYY-MM-DD>/<table>.parquet
+type ParquetSink struct {
+	dir string
+}
+
+func NewParquetSink(dir string) *ParquetSink {
+	return &ParquetSink{dir: dir}
+}
+
+func (p *ParquetSink) Name() string {
+	return "parquet"
+}
+
+func (p *ParquetSink) Prepare(ctx context.Context, dest sinks.Destination) (sinks.Batch, error) {
+	schema, err := ArrowSchema(dest.Schema)
+	if err != nil {
+		return nil, err
+	}
+	dateField := ""
+	for _, f := range dest.Schema {
+		for _, name := range dateFields {
+			if f.Name == name && dateField == "" {
+				dateField = name
+			}
+		}
+	}
+	// date dimension 이 없는 리포트는 요청 시작일로 파티션하므로 상대 날짜를 먼저 풉니다.
+	startDate := ""
+	if dateField == "" {
+		if startDate, err = config.ResolveDate(dest.StartDate, time.Now()); err != nil {
+			return nil, errors.Wrap(err, "failed to resolve partition date")
+		}
+	}
+	return &parquetBatch{
+		root:       filepath.Join(p.dir, dest.ReportType, "property_id="+dest.PropertyID),
+		dest:       dest,
+		schema:     schema,
+		dateField:  dateField,
+		startDate:  startDate,
+		partitions: make(map[string][]map[string]bigquery.Value),
+	}, nil
+}
+
+// ArrowSchema maps a BigQuery schema to an Arrow schema. Parquet stores the Arrow
+// string type as BYTE_ARRAY annotated UTF8 and date32 as DATE.
+func ArrowSchema(schema bigquery.Schema) (*arrow.Schema, error) {
+	fields := make([]arrow.Field, 0, len(schema))
+	for _, f := range schema {
+		var t arrow.DataType
+		switch f.Type {
+		case bigquery.IntegerFieldType:
+			t = arrow.PrimitiveTypes.Int64
+		case bigquery.FloatFieldType:
+			t = arrow.PrimitiveTypes.Float64
+		case bigquery.DateFieldType:
+			t = arrow.FixedWidthTypes.Date32
+		case bigquery.StringFieldType:
+			t = arrow.BinaryTypes.String
+		case bigquery.BooleanFieldType:
+			t = arrow.FixedWidthTypes.Boolean
+		default:
+			return nil, errors.Errorf("field %s: unsupported type %s", f.Name, f.Type)
+		}
+		fields = append(fields, arrow.Field{Name: f.Name, Type: t, Nullable: !f.Required})
+	}
+	return arrow.NewSchema(fields, nil), nil
+}
+
+type parquetBatch struct {
+	root      string
+	dest      sinks.Destination
+	schema    *arrow.Schema
+	dateField string
+	// startDate is the resolved StartDate, the partition of reports without a date field.
+	startDate  string
+	partitions map[string][]map[string]bigquery.Value
+	written    []string
+}
+
+func (b *parquetBatch) Write(ctx context.Context, rows []bigquery.ValueSaver) error {
+	for _, row := range rows {
+		values, _, err := row.Save()
+		if err != nil {
+			return errors.Wrap(err, "failed to save row")
+		}
+		date, err := b.partitionDate(values)
+		if err != nil {
+			return err
+		}
+		b.partitions[date] = append(b.partitions[date], values)
+	}
+	return nil
+}
+
+// partitionDate returns YYYY-MM-DD from the row's GA4 date (YYYYMMDD), or the
+// requested start date, resolved, when the report has no date dimension.
+func (b *parquetBatch) partitionDate(values map[string]bigquery.Value) (string, error) {
+	if b.dateField == "" {
+		return b.startDate, nil
+	}
+	raw := fmt.Sprint(values[b.dateField])
+	t, err := time.Parse("20060102", raw)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid %s %q", b.dateField, raw)
+	}
+	return t.Format("2006-01-02"), nil
+}
+
+// Commit writes one file per date partition, each to a temporary name that is renamed into place.
+func (b *parquetBatch) Commit(ctx context.Context) error {
+	dates := make([]string, 0, len(b.partitions))
+	for d := range b.partitions {
+		dates = append(dates, d)
+	}
+	sort.Strings(dates)
+
+	var temps []*atomicfile.File
+	defer func() {
+		for _, t := range temps {
+			t.Abort()
+		}
+	}()
+	for _, d := range dates {
+		tmp, err := b.writeFile(filepath.Join(b.root, "date="+d, b.dest.Table+".parquet"), b.partitions[d])
+		if err != nil {
+			return errors.Wrapf(err, "failed to write partition date=%s", d)
+		}
+		temps = append(temps, tmp)
+	}
+	for _, tmp := range temps {
+		if err := tmp.Commit(); err != nil {
+			return errors.Wrap(err, "failed to save parquet file")
+		}
+		b.written = append(b.written, tmp.Path())
+	}
+	logging.FromContext(ctx).Info("Data successfully saved", "files", len(b.written), "dir", b.root)
+	return nil
+}
+
+func (b *parquetBatch) writeFile(path string, rows []map[string]bigquery.Value) (*atomicfile.File, error) {
+	file, err := atomicfile.Create(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create parquet file")
+	}
+	if err := b.encode(file.File, rows); err != nil {
+		file.Abort()
+		return nil, err
+	}
+	return file, nil
+}
+
+func (b *parquetBatch) encode(file *os.File, rows []map[string]bigquery.Value) error {
+	rb := array.NewRecordBuilder(memory.DefaultAllocator, b.schema)
+	defer rb.Release()
+	for _, values := range rows {
+		typed, err := typedRow(b.dest.Schema, values)
+		if err != nil {
+			return err
+		}
+		for i, v := range typed {
+			appendValue(rb.Field(i), v)
+		}
+	}
+	rec := rb.NewRecord()
+	defer rec.Release()
+
+	props := parquet.NewWriterProperties(parquet.WithCompression(compress.Codecs.Snappy))
+	w, err := pqarrow.NewFileWriter(b.schema, file, props, pqarrow.DefaultWriterProps())
+	if err != nil {
+		return errors.Wrap(err, "failed to create parquet writer")
+	}
+	if err := w.Write(rec); err != nil {
+		w.Close()
+		return errors.Wrap(err, "failed to write parquet record")
+	}
+	// FileWriter.Close also closes the underlying file.
+	return errors.Wrap(w.Close(), "failed to close parquet writer")
+}
+
+// appendValue appends a value produced by typedValue to the matching column builder.
+func appendValue(builder array.Builder, v interface{}) {
+	if v == nil {
+		builder.AppendNull()
+		return
+	}
+	switch bld := builder.(type) {
+	case *array.Int64Builder:
+		bld.Append(v.(int64))
+	case *array.Float64Builder:
+		bld.Append(v.(float64))
+	case *array.Date32Builder:
+		bld.Append(arrow.Date32FromTime(v.(time.Time)))
+	case *array.BooleanBuilder:
+		bld.Append(v.(bool))
+	case *array.StringBuilder:
+		bld.Append(v.(string))
+	}
+}
+
+// Abort removes files already moved into place by a partially failed Commit.
+func (b *parquetBatch) Abort(ctx context.Context) error {
+	for _, path := range b.written {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "failed to remove parquet file")
+		}
+	}
+	b.written = nil
+	return nil
+}
@@ -0,0 +1,72 @@
+package impl
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/apache/arrow/go/v15/arrow/memory"
+	"github.com/apache/arrow/go/v15/parquet/file"
+	"github.com/apache/arrow/go/v15/parquet/pqarrow"
+
+	"go-ga4-to-bigquery/internal/config"
+	"go-ga4-to-bigquery/internal/sinks"
+)
+
+func TestParquetSink(t *testing.T) {
+	dir := t.TempDir()
+	if err := sinks.Write(context.Background(), NewParquetSink(dir), testDestination, testRows); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	for _, date := range []string{"2024-01-01", "2024-01-02"} {
+		path := filepath.Join(dir, "daily-test", "property_id=123", "date="+date, "ga4_daily_test.parquet")
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatalf("partition date=%s: %v", date, err)
+		}
+		rdr, err := file.NewParquetReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fr, err := pqarrow.NewFileReader(rdr, pqarrow.ArrowReadProperties{}, memory.DefaultAllocator)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tbl, err := fr.ReadTable(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tbl.NumRows() != 1 || tbl.NumCols() != 3 {
+			t.Errorf("date=%s: got %d rows x %d cols, want 1 x 3", date, tbl.NumRows(), tbl.NumCols())
+		}
+		if got := tbl.Schema().Field(1).Type.String(); got != "int64" {
+			t.Errorf("active_users type = %s, want int64", got)
+		}
+		tbl.Release()
+		rdr.Close()
+	}
+}
+
+func TestParquetSink_relativeStartDate(t *testing.T) {
+	dir := t.TempDir()
+	dest := testDestination
+	dest.Schema = bigquery.Schema{{Name: "active_users", Type: bigquery.IntegerFieldType}}
+	dest.StartDate = "7daysAgo"
+	rows := []bigquery.ValueSaver{testRow{"active_users": 12}}
+	if err := sinks.Write(context.Background(), NewParquetSink(dir), dest, rows); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	date, err := config.ResolveDate("7daysAgo", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "daily-test", "property_id=123", "date="+date, "ga4_daily_test.parquet")
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("partition date=%s: %v", date, err)
+	}
+}
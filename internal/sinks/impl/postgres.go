@@ -0,0 +1,310 @@
+package impl
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pkg/errors"
+
+	"go-ga4-to-bigquery/internal/logging"
+	"go-ga4-to-bigquery/internal/sinks"
+)
+
+// PostgresSink creates (or migrates) one table per report, named without the run-date
+// suffix, and loads rows with COPY. Rows are upserted on the report's natural key, so
+// re-running a date range updates the metrics instead of duplicating rows.
+type PostgresSink struct {
+	pool   *pgxpool.Pool
+	schema string
+}
+
+// NewPostgresSink connects to dsn. schema is the Postgres schema for the tables; empty means public.
+func NewPostgresSink(ctx context.Context, dsn, schema string) (*PostgresSink, error) {
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to connect to postgres")
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, errors.Wrap(err, "failed to connect to postgres")
+	}
+	if schema == "" {
+		schema = "public"
+	}
+	return &PostgresSink{pool: pool, schema: schema}, nil
+}
+
+func (p *PostgresSink) Name() string {
+	return "postgres"
+}
+
+// Close closes the connection pool.
+func (p *PostgresSink) Close() error {
+	p.pool.Close()
+	return nil
+}
+
+// PostgresType maps a BigQuery field type to a Postgres column type.
+func PostgresType(t bigquery.FieldType) (string, error) {
+	switch t {
+	case bigquery.IntegerFieldType:
+		return "BIGINT", nil
+	case bigquery.FloatFieldType:
+		return "DOUBLE PRECISION", nil
+	case bigquery.StringFieldType:
+		return "TEXT", nil
+	case bigquery.DateFieldType:
+		return "DATE", nil
+	case bigquery.BooleanFieldType:
+		return "BOOLEAN", nil
+	default:
+		return "", errors.Errorf("unsupported type %s", t)
+	}
+}
+
+// CreateTableDDL returns the CREATE TABLE statement for dest, keyed on dest.Key.
+func (p *PostgresSink) CreateTableDDL(dest sinks.Destination) (string, error) {
+	cols := make([]string, 0, len(dest.Schema)+1)
+	for _, f := range dest.Schema {
+		t, err := PostgresType(f.Type)
+		if err != nil {
+			return "", errors.Wrapf(err, "field %s", f.Name)
+		}
+		col := pgx.Identifier{f.Name}.Sanitize() + " " + t
+		if f.Required {
+			col += " NOT NULL"
+		}
+		cols = append(cols, col)
+	}
+	if len(dest.Key) > 0 {
+		cols = append(cols, "PRIMARY KEY ("+identList(dest.Key)+")")
+	}
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n\t%s\n)", p.table(dest), strings.Join(cols, ",\n\t")), nil
+}
+
+func (p *PostgresSink) table(dest sinks.Destination) string {
+	return pgx.Identifier{p.schema, dest.Table}.Sanitize()
+}
+
+func identList(names []string) string {
+	quoted := make([]string, len(names))
+	for i, n := range names {
+		quoted[i] = pgx.Identifier{n}.Sanitize()
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// Prepare opens a transaction, creates or migrates the table and a temporary
+// staging table that Write copies into.
+func (p *PostgresSink) Prepare(ctx context.Context, dest sinks.Destination) (sinks.Batch, error) {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to begin transaction")
+	}
+	batch := &postgresBatch{sink: p, tx: tx, dest: dest.Keyed()}
+	if err := batch.prepare(ctx); err != nil {
+		tx.Rollback(ctx)
+		return nil, err
+	}
+	return batch, nil
+}
+
+type postgresBatch struct {
+	sink *PostgresSink
+	tx   pgx.Tx
+	dest sinks.Destination
+	// rows are buffered until Commit, one per natural key, since ON CONFLICT cannot
+	// update the same row twice in one statement.
+	rows  [][]interface{}
+	byKey map[string]int
+}
+
+func (b *postgresBatch) prepare(ctx context.Context) error {
+	var existed bool
+	if err := b.tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM information_schema.tables
+		WHERE table_schema = $1 AND table_name = $2)`, b.sink.schema, b.dest.Table).Scan(&existed); err != nil {
+		return errors.Wrap(err, "failed to look up table")
+	}
+	ddl, err := b.sink.CreateTableDDL(b.dest)
+	if err != nil {
+		return err
+	}
+	if _, err := b.tx.Exec(ctx, ddl); err != nil {
+		return errors.Wrap(err, "failed to create table")
+	}
+	if err := b.migrate(ctx); err != nil {
+		return err
+	}
+	if existed && len(b.dest.Key) > 0 {
+		if err := b.ensureKey(ctx); err != nil {
+			return err
+		}
+	}
+	if _, err := b.tx.Exec(ctx, fmt.Sprintf("CREATE TEMP TABLE %s (LIKE %s INCLUDING DEFAULTS) ON COMMIT DROP",
+		b.stagingTable(), b.sink.table(b.dest))); err != nil {
+		return errors.Wrap(err, "failed to create staging table")
+	}
+	return nil
+}
+
+// ensureKey adds a unique index on the natural key to a table created without a
+// PRIMARY KEY, so that the ON CONFLICT upsert has a constraint to use.
+func (b *postgresBatch) ensureKey(ctx context.Context) error {
+	var hasKey bool
+	if err := b.tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM information_schema.table_constraints
+		WHERE table_schema = $1 AND table_name = $2 AND constraint_type = 'PRIMARY KEY')`,
+		b.sink.schema, b.dest.Table).Scan(&hasKey); err != nil {
+		return errors.Wrap(err, "failed to read table constraints")
+	}
+	if !hasKey {
+		index := pgx.Identifier{b.dest.Table + "_natural_key"}.Sanitize()
+		if _, err := b.tx.Exec(ctx, fmt.Sprintf("CREATE UNIQUE INDEX IF NOT EXISTS %s ON %s (%s)",
+			index, b.sink.table(b.dest), identList(b.dest.Key))); err != nil {
+			return errors.Wrap(err, "failed to create natural key index")
+		}
+	}
+	return nil
+}
+
+// migrate adds columns the report gained since the table was created. Changing the
+// type of an existing column is refused.
+func (b *postgresBatch) migrate(ctx context.Context) error {
+	rows, err := b.tx.Query(ctx, `SELECT column_name, upper(data_type) FROM information_schema.columns
+		WHERE table_schema = $1 AND table_name = $2`, b.sink.schema, b.dest.Table)
+	if err != nil {
+		return errors.Wrap(err, "failed to read table columns")
+	}
+	existing := make(map[string]string)
+	for rows.Next() {
+		var name, dataType string
+		if err := rows.Scan(&name, &dataType); err != nil {
+			rows.Close()
+			return errors.Wrap(err, "failed to read table columns")
+		}
+		existing[name] = dataType
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return errors.Wrap(err, "failed to read table columns")
+	}
+
+	for _, f := range b.dest.Schema {
+		t, err := PostgresType(f.Type)
+		if err != nil {
+			return errors.Wrapf(err, "field %s", f.Name)
+		}
+		current, ok := existing[f.Name]
+		if !ok {
+			if _, err := b.tx.Exec(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s",
+				b.sink.table(b.dest), pgx.Identifier{f.Name}.Sanitize(), t)); err != nil {
+				return errors.Wrapf(err, "failed to add column %s", f.Name)
+			}
+			logging.FromContext(ctx).Info("Added column", "column", f.Name, "type", t, "table", b.dest.Table)
+			continue
+		}
+		if current != t {
+			return errors.Errorf("column %s is %s in postgres but %s in the report schema", f.Name, current, t)
+		}
+	}
+	return nil
+}
+
+func (b *postgresBatch) stagingTable() string {
+	return pgx.Identifier{"staging_" + b.dest.Table}.Sanitize()
+}
+
+func (b *postgresBatch) columns() []string {
+	cols := make([]string, len(b.dest.Schema))
+	for i, f := range b.dest.Schema {
+		cols[i] = f.Name
+	}
+	return cols
+}
+
+// Write buffers the rows. A later row replaces an earlier one with the same natural key.
+func (b *postgresBatch) Write(ctx context.Context, rows []bigquery.ValueSaver) error {
+	if b.byKey == nil {
+		b.byKey = make(map[string]int)
+	}
+	for _, row := range rows {
+		values, _, err := row.Save()
+		if err != nil {
+			return errors.Wrap(err, "failed to save row")
+		}
+		typed, err := typedRow(b.dest.Schema, values)
+		if err != nil {
+			return err
+		}
+		if len(b.dest.Key) == 0 {
+			b.rows = append(b.rows, typed)
+			continue
+		}
+		key := b.naturalKey(values)
+		if i, ok := b.byKey[key]; ok {
+			b.rows[i] = typed
+			continue
+		}
+		b.byKey[key] = len(b.rows)
+		b.rows = append(b.rows, typed)
+	}
+	return nil
+}
+
+func (b *postgresBatch) naturalKey(values map[string]bigquery.Value) string {
+	parts := make([]string, len(b.dest.Key))
+	for i, k := range b.dest.Key {
+		parts[i] = fmt.Sprint(values[k])
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// UpsertSQL moves the staged rows into the table, updating non-key columns on conflict.
+func (b *postgresBatch) UpsertSQL() string {
+	cols := identList(b.columns())
+	stmt := fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s", b.sink.table(b.dest), cols, cols, b.stagingTable())
+	if len(b.dest.Key) == 0 {
+		return stmt
+	}
+	isKey := make(map[string]bool, len(b.dest.Key))
+	for _, k := range b.dest.Key {
+		isKey[k] = true
+	}
+	var updates []string
+	for _, c := range b.columns() {
+		if !isKey[c] {
+			q := pgx.Identifier{c}.Sanitize()
+			updates = append(updates, q+" = EXCLUDED."+q)
+		}
+	}
+	stmt += " ON CONFLICT (" + identList(b.dest.Key) + ")"
+	if len(updates) == 0 {
+		return stmt + " DO NOTHING"
+	}
+	return stmt + " DO UPDATE SET " + strings.Join(updates, ", ")
+}
+
+func (b *postgresBatch) Commit(ctx context.Context) error {
+	if _, err := b.tx.CopyFrom(ctx, pgx.Identifier{"staging_" + b.dest.Table}, b.columns(), pgx.CopyFromRows(b.rows)); err != nil {
+		return errors.Wrap(err, "failed to copy rows")
+	}
+	tag, err := b.tx.Exec(ctx, b.UpsertSQL())
+	if err != nil {
+		return errors.Wrap(err, "failed to upsert rows")
+	}
+	if err := b.tx.Commit(ctx); err != nil {
+		return errors.Wrap(err, "failed to commit transaction")
+	}
+	logging.FromContext(ctx).Info("Upserted rows", "rows", tag.RowsAffected(), "table", b.sink.table(b.dest))
+	return nil
+}
+
+func (b *postgresBatch) Abort(ctx context.Context) error {
+	if err := b.tx.Rollback(ctx); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+		return errors.Wrap(err, "failed to roll back transaction")
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+package impl
+
+import (
+	"cloud.google.com/go/bigquery"
+	_ "modernc.org/sqlite"
+)
+
+func init() {
+	localDialects["sqlite"] = localDialect{
+		driver: "sqlite",
+		types: map[bigquery.FieldType]string{
+			bigquery.IntegerFieldType: "INTEGER",
+			bigquery.FloatFieldType:   "REAL",
+			bigquery.StringFieldType:  "TEXT",
+			bigquery.DateFieldType:    "DATE",
+			bigquery.BooleanFieldType: "BOOLEAN",
+		},
+	}
+}
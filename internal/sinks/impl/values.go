@@ -0,0 +1,72 @@
+package impl
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/civil"
+	"github.com/pkg/errors"
+)
+
+// typedValue converts a Save() value to the Go type matching the field's BigQuery
+// type: int64, float64, bool, time.Time (DATE) or string. GA4 returns every value
+// as a string, so numeric strings are parsed here. nil stays nil for nullable fields.
+func typedValue(f *bigquery.FieldSchema, v bigquery.Value) (interface{}, error) {
+	if v == nil {
+		if f.Required {
+			return nil, errors.New("missing value for required field")
+		}
+		return nil, nil
+	}
+	switch f.Type {
+	case bigquery.IntegerFieldType:
+		switch t := v.(type) {
+		case int:
+			return int64(t), nil
+		case int64:
+			return t, nil
+		}
+		return strconv.ParseInt(fmt.Sprint(v), 10, 64)
+	case bigquery.FloatFieldType:
+		switch t := v.(type) {
+		case float64:
+			return t, nil
+		case int:
+			return float64(t), nil
+		}
+		return strconv.ParseFloat(fmt.Sprint(v), 64)
+	case bigquery.BooleanFieldType:
+		if t, ok := v.(bool); ok {
+			return t, nil
+		}
+		return strconv.ParseBool(fmt.Sprint(v))
+	case bigquery.DateFieldType:
+		if t, ok := v.(civil.Date); ok {
+			return t.In(time.UTC), nil
+		}
+		d, err := civil.ParseDate(fmt.Sprint(v))
+		if err != nil {
+			return nil, err
+		}
+		return d.In(time.UTC), nil
+	case bigquery.StringFieldType:
+		return fmt.Sprint(v), nil
+	default:
+		return nil, errors.Errorf("unsupported type %s", f.Type)
+	}
+}
+
+// typedRow converts every schema field of a Save() map with typedValue, in schema order.
+func typedRow(schema bigquery.Schema, values map[string]bigquery.Value) ([]interface{}, error) {
+	row := make([]interface{}, len(schema))
+	for i, f := range schema {
+		v, err := typedValue(f, values[f.Name])
+		if err != nil {
+			return nil, errors.Wrapf(err, "field %s", f.Name)
+		}
+		row[i] = v
+	}
+	return row, nil
+}
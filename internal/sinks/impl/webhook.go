@@ -0,0 +1,195 @@
+package impl
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/pkg/errors"
+
+	"go-ga4-to-bigquery/internal/logging"
+	"go-ga4-to-bigquery/internal/sinks"
+)
+
+// SignatureHeader carries the hex HMAC-SHA256 of the request body, prefixed with "sha256=".
+const SignatureHeader = "X-Ga4bq-Signature-256"
+
+// DefaultWebhookRetries asks NewWebhookSink for its default of 3 retries.
+const DefaultWebhookRetries = -1
+
+// WebhookOptions configures a WebhookSink.
+type WebhookOptions struct {
+	URL string
+	// BatchSize is the number of rows per request; 0 means 500.
+	BatchSize int
+	Headers   map[string]string
+	// HMACSecret signs every body with SignatureHeader when set.
+	HMACSecret string
+	// MaxRetries is how often a 5xx or network failure is retried; 0 disables retries
+	// and DefaultWebhookRetries (-1) means 3.
+	MaxRetries int
+	// RetryBackoff is the first retry delay, doubled on every attempt; 0 means 1s.
+	RetryBackoff time.Duration
+	Client       *http.Client
+}
+
+// WebhookPayload is the JSON body of every request.
+type WebhookPayload struct {
+	PropertyID string                      `json:"property_id"`
+	ReportType string                      `json:"report_type"`
+	Table      string                      `json:"table"`
+	StartDate  string                      `json:"start_date"`
+	EndDate    string                      `json:"end_date"`
+	Batch      int                         `json:"batch"`
+	Rows       []map[string]bigquery.Value `json:"rows"`
+}
+
+// WebhookSink POSTs report rows as JSON batches to an HTTP endpoint.
+type WebhookSink struct {
+	opts WebhookOptions
+}
+
+func NewWebhookSink(opts WebhookOptions) *WebhookSink {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 500
+	}
+	if opts.MaxRetries < 0 {
+		opts.MaxRetries = 3
+	}
+	if opts.RetryBackoff <= 0 {
+		opts.RetryBackoff = time.Second
+	}
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &WebhookSink{opts: opts}
+}
+
+func (w *WebhookSink) Name() string {
+	return "webhook"
+}
+
+func (w *WebhookSink) Prepare(ctx context.Context, dest sinks.Destination) (sinks.Batch, error) {
+	return &webhookBatch{sink: w, dest: dest}, nil
+}
+
+// webhookBatch buffers rows and only pushes them on Commit, so an aborted fan-out
+// sends nothing. Batches already delivered when a later one fails cannot be recalled.
+type webhookBatch struct {
+	sink *WebhookSink
+	dest sinks.Destination
+	rows []map[string]bigquery.Value
+}
+
+func (b *webhookBatch) Write(ctx context.Context, rows []bigquery.ValueSaver) error {
+	for _, row := range rows {
+		values, _, err := row.Save()
+		if err != nil {
+			return errors.Wrap(err, "failed to save row")
+		}
+		b.rows = append(b.rows, values)
+	}
+	return nil
+}
+
+func (b *webhookBatch) Commit(ctx context.Context) error {
+	size := b.sink.opts.BatchSize
+	for i, n := 0, 0; i < len(b.rows); i, n = i+size, n+1 {
+		end := i + size
+		if end > len(b.rows) {
+			end = len(b.rows)
+		}
+		body, err := json.Marshal(WebhookPayload{
+			PropertyID: b.dest.PropertyID,
+			ReportType: b.dest.ReportType,
+			Table:      b.dest.Table,
+			StartDate:  b.dest.StartDate,
+			EndDate:    b.dest.EndDate,
+			Batch:      n,
+			Rows:       b.rows[i:end],
+		})
+		if err != nil {
+			return errors.Wrap(err, "failed to encode webhook payload")
+		}
+		if err := b.sink.post(ctx, body); err != nil {
+			return errors.Wrapf(err, "failed to deliver batch %d", n)
+		}
+	}
+	logging.FromContext(ctx).Info("Delivered rows to webhook", "rows", len(b.rows), "table", b.dest.Table)
+	return nil
+}
+
+func (b *webhookBatch) Abort(ctx context.Context) error {
+	b.rows = nil
+	return nil
+}
+
+// post sends body, retrying 5xx responses and network errors with exponential backoff.
+func (w *WebhookSink) post(ctx context.Context, body []byte) error {
+	backoff := w.opts.RetryBackoff
+	var lastErr error
+	for attempt := 0; attempt <= w.opts.MaxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+
+		retry, err := w.send(ctx, body)
+		if err == nil {
+			return nil
+		}
+		if !retry {
+			return err
+		}
+		lastErr = err
+		logging.FromContext(ctx).Warn("Webhook attempt failed", "attempt", attempt+1, "error", err)
+	}
+	return errors.Wrapf(lastErr, "giving up after %d attempt(s)", w.opts.MaxRetries+1)
+}
+
+func (w *WebhookSink) send(ctx context.Context, body []byte) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.opts.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, errors.Wrap(err, "failed to create request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range w.opts.Headers {
+		req.Header.Set(k, v)
+	}
+	if w.opts.HMACSecret != "" {
+		req.Header.Set(SignatureHeader, Sign(w.opts.HMACSecret, body))
+	}
+
+	resp, err := w.opts.Client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, errors.Wrap(err, "request failed")
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+
+	switch {
+	case resp.StatusCode >= 500:
+		return true, errors.Errorf("server error %s: %s", resp.Status, msg)
+	case resp.StatusCode >= 300:
+		return false, errors.Errorf("rejected with %s: %s", resp.Status, msg)
+	}
+	return false, nil
+}
+
+// Sign returns the SignatureHeader value for body: "sha256=" + hex(HMAC-SHA256(secret, body)).
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,101 @@
+package impl
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"go-ga4-to-bigquery/internal/sinks"
+)
+
+func TestWebhookSink(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		attempts int
+		payloads []WebhookPayload
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		// the first request fails once to exercise the retry
+		if attempts == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		if got, want := r.Header.Get(SignatureHeader), Sign("s3cret", body); got != want {
+			t.Errorf("signature = %q, want %q", got, want)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer token" {
+			t.Errorf("Authorization = %q", got)
+		}
+		var p WebhookPayload
+		if err := json.Unmarshal(body, &p); err != nil {
+			t.Error(err)
+		}
+		payloads = append(payloads, p)
+	}))
+	defer srv.Close()
+
+	sink := NewWebhookSink(WebhookOptions{
+		URL:          srv.URL,
+		BatchSize:    1,
+		Headers:      map[string]string{"Authorization": "Bearer token"},
+		HMACSecret:   "s3cret",
+		MaxRetries:   DefaultWebhookRetries,
+		RetryBackoff: time.Millisecond,
+	})
+	if err := sinks.Write(context.Background(), sink, testDestination, testRows); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	if attempts != 3 {
+		t.Errorf("attempts = %d, want 3 (one retry)", attempts)
+	}
+	if len(payloads) != 2 || payloads[1].Batch != 1 || len(payloads[1].Rows) != 1 {
+		t.Fatalf("payloads = %+v, want two batches of one row", payloads)
+	}
+	if payloads[0].Rows[0]["date"] != "20240101" || payloads[0].Table != "ga4_daily_test" {
+		t.Errorf("first payload = %+v", payloads[0])
+	}
+}
+
+func TestWebhookSink_ClientErrorIsNotRetried(t *testing.T) {
+	attempts := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		http.Error(w, "bad payload", http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	sink := NewWebhookSink(WebhookOptions{URL: srv.URL, RetryBackoff: time.Millisecond})
+	if err := sinks.Write(context.Background(), sink, testDestination, testRows); err == nil {
+		t.Fatal("Write() error = nil, want 400 error")
+	}
+	if attempts != 1 {
+		t.Errorf("attempts = %d, want 1", attempts)
+	}
+}
+
+func TestWebhookSink_NoRetries(t *testing.T) {
+	attempts := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	sink := NewWebhookSink(WebhookOptions{URL: srv.URL, MaxRetries: 0, RetryBackoff: time.Millisecond})
+	if err := sinks.Write(context.Background(), sink, testDestination, testRows); err == nil {
+		t.Fatal("Write() error = nil, want 503 error")
+	}
+	if attempts != 1 {
+		t.Errorf("attempts = %d, want 1 with MaxRetries 0", attempts)
+	}
+}
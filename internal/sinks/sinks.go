@@ -0,0 +1,178 @@
+package sinks
+
+import (
+	"context"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/pkg/errors"
+
+	"go-ga4-to-bigquery/internal/logging"
+)
+
+// Destination 은 하나의 리포트 결과를 어디에 어떤 스키마로 쓸지 설명합니다.
+type Destination struct {
+	PropertyID string
+	ReportType string
+	// Table is the destination name, TABLE_PREFIX + DefaultTable + _YYYYMMDD.
+	Table string
+	// KeyedTable is Table without the date suffix, TABLE_PREFIX + DefaultTable.
+	KeyedTable string
+	Schema     bigquery.Schema
+	// Key is the report's natural key, the dimension columns of Schema.
+	Key       []string
+	StartDate string
+	EndDate   string
+	// RunID identifies the pipeline run, e.g. in staging table names.
+	RunID string
+	// FullRefresh asks the sink to replace the destination atomically instead of appending.
+	FullRefresh bool
+	// ExpectedRows is the row count GA4 reported for the request, or -1 when unknown.
+	ExpectedRows int64
+}
+
+// Keyed returns d loading into KeyedTable, for sinks that upsert on Key so that a re-run
+// on a later day updates the rows instead of creating another table. Destinations
+// without a KeyedTable keep Table.
+func (d Destination) Keyed() Destination {
+	if d.KeyedTable != "" {
+		d.Table = d.KeyedTable
+	}
+	return d
+}
+
+// Sink prepares destinations for report rows.
+type Sink interface {
+	Name() string
+	Prepare(ctx context.Context, dest Destination) (Batch, error)
+}
+
+// Batch receives the rows of one report. Nothing is visible to readers of the
+// destination until Commit; Abort discards whatever Prepare and Write created.
+type Batch interface {
+	Write(ctx context.Context, rows []bigquery.ValueSaver) error
+	Commit(ctx context.Context) error
+	Abort(ctx context.Context) error
+}
+
+// Planner is implemented by sinks that can describe what Prepare and Commit would do
+// for dest without creating or writing anything. It backs run-report --dry-run.
+type Planner interface {
+	Plan(ctx context.Context, dest Destination) (Plan, error)
+}
+
+// Plan describes the effect of loading one report into one sink.
+type Plan struct {
+	Output string `json:"output"`
+	Table  string `json:"table"`
+	// Exists is set when the destination is already there; DDL is only set when it is not.
+	Exists        bool     `json:"exists"`
+	DDL           string   `json:"ddl,omitempty"`
+	SchemaChanges []string `json:"schema_changes,omitempty"`
+	// Steps describes the load in order, e.g. the staging table and copy of a full refresh.
+	Steps   []string       `json:"steps,omitempty"`
+	Queries []PlannedQuery `json:"queries,omitempty"`
+}
+
+// PlannedQuery is a statement the sink would run, with the bytes the warehouse
+// estimates it would process.
+type PlannedQuery struct {
+	SQL            string `json:"sql"`
+	EstimatedBytes int64  `json:"estimated_bytes"`
+}
+
+// Write prepares dest on sink, writes rows and commits, aborting on any failure.
+func Write(ctx context.Context, sink Sink, dest Destination, rows []bigquery.ValueSaver) error {
+	batch, err := sink.Prepare(ctx, dest)
+	if err != nil {
+		return errors.Wrapf(err, "failed to prepare %s", sink.Name())
+	}
+	if err := batch.Write(ctx, rows); err != nil {
+		abort(ctx, sink.Name(), batch)
+		return errors.Wrapf(err, "failed to write to %s", sink.Name())
+	}
+	if err := batch.Commit(ctx); err != nil {
+		abort(ctx, sink.Name(), batch)
+		return errors.Wrapf(err, "failed to commit %s", sink.Name())
+	}
+	return nil
+}
+
+func abort(ctx context.Context, name string, batch Batch) {
+	if err := batch.Abort(ctx); err != nil {
+		logging.FromContext(ctx).Warn("Failed to abort sink", "sink", name, "error", err)
+	}
+}
+
+// Multi fans every call out to all sinks. A failure in any of them aborts the
+// batches that have not been committed yet.
+func Multi(sinks ...Sink) Sink {
+	if len(sinks) == 1 {
+		return sinks[0]
+	}
+	return multiSink(sinks)
+}
+
+type multiSink []Sink
+
+func (m multiSink) Name() string {
+	name := ""
+	for i, s := range m {
+		if i > 0 {
+			name += ","
+		}
+		name += s.Name()
+	}
+	return name
+}
+
+func (m multiSink) Prepare(ctx context.Context, dest Destination) (Batch, error) {
+	batches := make(multiBatch, 0, len(m))
+	for _, s := range m {
+		b, err := s.Prepare(ctx, dest)
+		if err != nil {
+			batches.Abort(ctx)
+			return nil, errors.Wrapf(err, "failed to prepare %s", s.Name())
+		}
+		batches = append(batches, namedBatch{name: s.Name(), Batch: b})
+	}
+	return &batches, nil
+}
+
+type namedBatch struct {
+	name string
+	Batch
+}
+
+type multiBatch []namedBatch
+
+func (m *multiBatch) Write(ctx context.Context, rows []bigquery.ValueSaver) error {
+	for _, b := range *m {
+		if err := b.Write(ctx, rows); err != nil {
+			return errors.Wrapf(err, "failed to write to %s", b.name)
+		}
+	}
+	return nil
+}
+
+// Commit commits in order. Batches already committed when a later one fails stay committed.
+func (m *multiBatch) Commit(ctx context.Context) error {
+	for len(*m) > 0 {
+		b := (*m)[0]
+		if err := b.Commit(ctx); err != nil {
+			return errors.Wrapf(err, "failed to commit %s", b.name)
+		}
+		*m = (*m)[1:]
+	}
+	return nil
+}
+
+func (m *multiBatch) Abort(ctx context.Context) error {
+	var first error
+	for _, b := range *m {
+		if err := b.Abort(ctx); err != nil && first == nil {
+			first = errors.Wrapf(err, "failed to abort %s", b.name)
+		}
+	}
+	*m = nil
+	return first
+}
@@ -0,0 +1,90 @@
+package sinks
+
+import (
+	"context"
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/pkg/errors"
+)
+
+type recordingSink struct {
+	name   string
+	failOn string
+	events *[]string
+}
+
+func (r *recordingSink) Name() string { return r.name }
+
+func (r *recordingSink) Prepare(ctx context.Context, dest Destination) (Batch, error) {
+	*r.events = append(*r.events, r.name+":prepare")
+	if r.failOn == "prepare" {
+		return nil, errors.New("prepare failed")
+	}
+	return r, nil
+}
+
+func (r *recordingSink) Write(ctx context.Context, rows []bigquery.ValueSaver) error {
+	*r.events = append(*r.events, r.name+":write")
+	if r.failOn == "write" {
+		return errors.New("write failed")
+	}
+	return nil
+}
+
+func (r *recordingSink) Commit(ctx context.Context) error {
+	*r.events = append(*r.events, r.name+":commit")
+	return nil
+}
+
+func (r *recordingSink) Abort(ctx context.Context) error {
+	*r.events = append(*r.events, r.name+":abort")
+	return nil
+}
+
+func TestMulti(t *testing.T) {
+	tests := []struct {
+		name    string
+		failOn  string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "fan out",
+			want: []string{"a:prepare", "b:prepare", "a:write", "b:write", "a:commit", "b:commit"},
+		},
+		{
+			name:    "write failure aborts every batch",
+			failOn:  "write",
+			want:    []string{"a:prepare", "b:prepare", "a:write", "b:write", "a:abort", "b:abort"},
+			wantErr: true,
+		},
+		{
+			name:    "prepare failure aborts prepared batches",
+			failOn:  "prepare",
+			want:    []string{"a:prepare", "b:prepare", "a:abort"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []string
+			sink := Multi(
+				&recordingSink{name: "a", events: &events},
+				&recordingSink{name: "b", failOn: tt.failOn, events: &events},
+			)
+			err := Write(context.Background(), sink, Destination{}, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Write() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(events) != len(tt.want) {
+				t.Fatalf("events = %v, want %v", events, tt.want)
+			}
+			for i := range events {
+				if events[i] != tt.want[i] {
+					t.Fatalf("events = %v, want %v", events, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package ga4bq
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/pkg/errors"
+	ga "google.golang.org/api/analyticsdata/v1beta"
+)
+
+// RunsTable is the dataset table every report execution is recorded in.
+const RunsTable = "_ingestion_runs"
+
+// Run statuses recorded in RunsTable.
+const (
+	RunSucceeded = "succeeded"
+	RunFailed    = "failed"
+)
+
+// runRecord is one row of RunsTable, filled in while a report runs.
+type runRecord struct {
+	runID       string
+	propertyID  string
+	reportType  string
+	startDate   string
+	endDate     string
+	requestHash string
+	rowsFetched int
+	rowsLoaded  int
+	quotaTokens int64
+	table       string
+	startedAt   time.Time
+	duration    time.Duration
+	err         error
+}
+
+var runsSchema = bigquery.Schema{
+	{Name: "run_id", Type: bigquery.StringFieldType, Required: true},
+	{Name: "started_at", Type: bigquery.TimestampFieldType, Required: true},
+	{Name: "property_id", Type: bigquery.StringFieldType, Required: true},
+	{Name: "report_type", Type: bigquery.StringFieldType, Required: true},
+	{Name: "start_date", Type: bigquery.StringFieldType},
+	{Name: "end_date", Type: bigquery.StringFieldType},
+	{Name: "request_hash", Type: bigquery.StringFieldType},
+	{Name: "rows_fetched", Type: bigquery.IntegerFieldType, Required: true},
+	{Name: "rows_loaded", Type: bigquery.IntegerFieldType, Required: true},
+	{Name: "quota_tokens", Type: bigquery.IntegerFieldType, Required: true},
+	{Name: "duration_ms", Type: bigquery.IntegerFieldType, Required: true},
+	{Name: "destination_table", Type: bigquery.StringFieldType},
+	{Name: "status", Type: bigquery.StringFieldType, Required: true},
+	{Name: "error_message", Type: bigquery.StringFieldType},
+}
+
+func (r *runRecord) Save() (map[string]bigquery.Value, string, error) {
+	status, message := RunSucceeded, ""
+	if r.err != nil {
+		status, message = RunFailed, r.err.Error()
+	}
+	return map[string]bigquery.Value{
+		"run_id":            r.runID,
+		"started_at":        r.startedAt,
+		"property_id":       r.propertyID,
+		"report_type":       r.reportType,
+		"start_date":        r.startDate,
+		"end_date":          r.endDate,
+		"request_hash":      r.requestHash,
+		"rows_fetched":      r.rowsFetched,
+		"rows_loaded":       r.rowsLoaded,
+		"quota_tokens":      r.quotaTokens,
+		"duration_ms":       r.duration.Milliseconds(),
+		"destination_table": r.table,
+		"status":            status,
+		"error_message":     message,
+	}, bigquery.NoDedupeID, nil
+}
+
+// audited reports whether report executions are recorded, which needs a BigQuery output.
+func (p *Pipeline) audited() bool {
+	return p.bqClient != nil
+}
+
+// withQuota asks GA4 to return the property quota the request consumed and what remains.
+func withQuota(request RequestFunc) RequestFunc {
+	return func(propertyId, startDate, endDate string) *ga.RunReportRequest {
+		r := request(propertyId, startDate, endDate)
+		r.ReturnPropertyQuota = true
+		return r
+	}
+}
+
+// quotaTokens returns the daily tokens a response consumed, zero when GA4 did not say.
+func quotaTokens(result *ga.RunReportResponse) int64 {
+	if result.PropertyQuota == nil || result.PropertyQuota.TokensPerDay == nil {
+		return 0
+	}
+	return result.PropertyQuota.TokensPerDay.Consumed
+}
+
+// recordRun appends the record to RunsTable. A failure to record is logged and does
+// not change the outcome of the run.
+func (p *Pipeline) recordRun(ctx context.Context, rec *runRecord) {
+	if err := p.insertRun(ctx, rec); err != nil {
+		p.logger.Warn("Failed to record run", "report", rec.reportType, "table", RunsTable, "error", err)
+	}
+}
+
+func (p *Pipeline) insertRun(ctx context.Context, rec *runRecord) error {
+	table := p.bqClient.Dataset(p.cfg.DatasetID).Table(RunsTable)
+	if err := table.Create(ctx, &bigquery.TableMetadata{
+		Schema:           runsSchema,
+		Labels:           p.labels(),
+		TimePartitioning: &bigquery.TimePartitioning{Field: "started_at", Type: bigquery.DayPartitioningType},
+	}); err != nil && !isAlreadyExists(err) {
+		return errors.Wrap(err, "failed to create runs table")
+	}
+	return errors.Wrap(table.Inserter().Put(ctx, rec), "failed to insert run")
+}
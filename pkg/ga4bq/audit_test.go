@@ -0,0 +1,34 @@
+package ga4bq
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRunRecord_Save(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantStatus  string
+		wantMessage string
+	}{
+		{name: "succeeded", wantStatus: RunSucceeded},
+		{name: "failed", err: errors.New("quota exhausted"), wantStatus: RunFailed, wantMessage: "quota exhausted"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &runRecord{runID: "r", reportType: "daily-events", duration: 1500 * time.Millisecond, err: tt.err}
+			row, _, err := rec.Save()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if row["status"] != tt.wantStatus || row["error_message"] != tt.wantMessage {
+				t.Errorf("status = %v, error_message = %v, want %s, %q", row["status"], row["error_message"], tt.wantStatus, tt.wantMessage)
+			}
+			if row["duration_ms"] != int64(1500) {
+				t.Errorf("duration_ms = %v, want 1500", row["duration_ms"])
+			}
+		})
+	}
+}
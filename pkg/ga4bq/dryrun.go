@@ -0,0 +1,117 @@
+package ga4bq
+
+import (
+	"context"
+
+	ga "google.golang.org/api/analyticsdata/v1beta"
+
+	"go-ga4-to-bigquery/internal/config"
+	"go-ga4-to-bigquery/internal/sinks"
+	sinkimpl "go-ga4-to-bigquery/internal/sinks/impl"
+)
+
+// DryRunReport is what Run would do for one report.
+type DryRunReport struct {
+	ReportType string               `json:"report_type"`
+	Request    *ga.RunReportRequest `json:"request"`
+	// Rows is the row count GA4 reports for the request; only set when fetching.
+	Rows  *int64 `json:"rows,omitempty"`
+	Plans []Plan `json:"outputs"`
+}
+
+// DryRun builds every report's request and the plan of each of its outputs without
+// creating or writing anything. With fetch, the requests are also run against GA4 to
+// count rows; the response cache and archive are bypassed. BigQuery queries in a plan
+// are estimated with a BigQuery dry-run.
+func (p *Pipeline) DryRun(ctx context.Context, fetch bool) ([]DryRunReport, error) {
+	if fetch && p.fetcher == nil {
+		if err := p.openGA4(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if p.cfg.HasOutput(config.OutputBigQuery) && p.bqClient == nil {
+		if err := p.openBigQuery(ctx, false); err != nil {
+			return nil, err
+		}
+	}
+
+	var out []DryRunReport
+	for _, reportType := range p.cfg.ReportTypes {
+		report, err := p.registry.New(reportType)
+		if err != nil {
+			return out, stageError(StageConfig, err, "failed to select report")
+		}
+		// Run 과 같은 날짜와 요청 wrapper 로 요청을 만듭니다.
+		start, end, err := p.requestRange()
+		if err != nil {
+			return out, stageError(StageConfig, err, "failed to resolve date range")
+		}
+		request := p.requestFunc(reportType, report)
+		r := DryRunReport{
+			ReportType: reportType,
+			Request:    request(p.cfg.PropertyID, start, end),
+		}
+		if fetch {
+			result, err := p.fetchUncached(ctx, request, start, end)
+			if err != nil {
+				return out, stageError(StageFetch, err, "failed to get GA data for "+reportType)
+			}
+			rows := result.RowCount
+			r.Rows = &rows
+		}
+
+		dest := p.destination(reportType, report, p.cfg.InitialFetchFromDate, p.cfg.FetchToDate)
+		for _, name := range p.cfg.OutputsFor(reportType) {
+			plan, err := p.plan(ctx, name, dest)
+			if err != nil {
+				return out, stageError(StageLoad, err, "failed to plan output "+name)
+			}
+			r.Plans = append(r.Plans, plan)
+		}
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+// fetchUncached runs the request without reading or filling the response cache.
+func (p *Pipeline) fetchUncached(ctx context.Context, request RequestFunc, start, end string) (*ga.RunReportResponse, error) {
+	if f, ok := p.fetcher.(*GA4Fetcher); ok {
+		return f.GetGADataFetcher(ctx, p.cfg.PropertyID, start, end, request)
+	}
+	result, _, err := p.fetcher.Fetch(ctx, p.cfg.PropertyID, start, end, request)
+	return result, err
+}
+
+// plan asks the output's sink for a plan. Sinks are not opened for a dry-run since some
+// create files on open, so only sinks given with WithSink and BigQuery are asked.
+func (p *Pipeline) plan(ctx context.Context, name string, dest Destination) (Plan, error) {
+	var planner sinks.Planner
+	if s, ok := p.sinks[name].(sinks.Planner); ok {
+		planner = s
+	} else if name == config.OutputBigQuery {
+		planner = p.bigQuerySink()
+	}
+	if planner == nil {
+		switch name {
+		case config.OutputPostgres, config.OutputSQLite, config.OutputDuckDB:
+			dest = dest.Keyed()
+		}
+		return Plan{Output: name, Table: dest.Table}, nil
+	}
+
+	plan, err := planner.Plan(ctx, dest)
+	if err != nil {
+		return plan, err
+	}
+	// 데이터셋이 아직 없으면 BigQuery dry-run 도 실패하므로 추정하지 않습니다.
+	if plan.Output == config.OutputBigQuery && p.bqClient != nil && !p.datasetPending {
+		for i, q := range plan.Queries {
+			bytes, err := sinkimpl.EstimateQueryBytes(ctx, p.bqClient, q.SQL)
+			if err != nil {
+				return plan, err
+			}
+			plan.Queries[i].EstimatedBytes = bytes
+		}
+	}
+	return plan, nil
+}
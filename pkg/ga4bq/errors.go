@@ -0,0 +1,83 @@
+package ga4bq
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// Stage 는 실행 중 어느 단계에서 실패했는지를 나타냅니다.
+type Stage int
+
+const (
+	StageConfig Stage = iota + 1
+	StageAuth
+	StageFetch
+	StageTransform
+	StageLoad
+	StageReconcile
+)
+
+func (s Stage) String() string {
+	switch s {
+	case StageConfig:
+		return "config"
+	case StageAuth:
+		return "auth"
+	case StageFetch:
+		return "fetch"
+	case StageTransform:
+		return "transform"
+	case StageLoad:
+		return "load"
+	case StageReconcile:
+		return "reconcile"
+	default:
+		return "unknown"
+	}
+}
+
+// exit codes per stage. 1 is left for errors that do not carry a stage.
+var exitCodes = map[Stage]int{
+	StageConfig:    2,
+	StageAuth:      3,
+	StageFetch:     4,
+	StageTransform: 5,
+	StageLoad:      6,
+	StageReconcile: 7,
+}
+
+// StageError tags an error with the stage it happened in.
+type StageError struct {
+	Stage Stage
+	Err   error
+}
+
+func (e *StageError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Stage, e.Err)
+}
+
+func (e *StageError) Unwrap() error {
+	return e.Err
+}
+
+func stageError(stage Stage, err error, message string) error {
+	if err == nil {
+		return nil
+	}
+	return &StageError{Stage: stage, Err: errors.Wrap(err, message)}
+}
+
+// ExitCode maps an error returned by a Pipeline to a process exit code.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var se *StageError
+	if errors.As(err, &se) {
+		if code, ok := exitCodes[se.Stage]; ok {
+			return code
+		}
+	}
+	return 1
+}
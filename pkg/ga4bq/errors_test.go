@@ -0,0 +1,29 @@
+package ga4bq
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "nil", err: nil, want: 0},
+		{name: "untyped", err: errors.New("boom"), want: 1},
+		{name: "config", err: stageError(StageConfig, errors.New("boom"), "invalid config"), want: 2},
+		{name: "auth", err: stageError(StageAuth, errors.New("boom"), "denied"), want: 3},
+		{name: "fetch wrapped", err: errors.WithMessage(stageError(StageFetch, errors.New("boom"), "fetch"), "report"), want: 4},
+		{name: "load", err: stageError(StageLoad, errors.New("boom"), "load"), want: 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExitCode(tt.err); got != tt.want {
+				t.Errorf("ExitCode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package ga4bq
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	ga "google.golang.org/api/analyticsdata/v1beta"
+
+	"go-ga4-to-bigquery/internal/cache"
+	"go-ga4-to-bigquery/internal/logging"
+	"go-ga4-to-bigquery/internal/metrics"
+)
+
+// RequestFunc builds the GA4 request of a report for a property and date range.
+type RequestFunc = func(propertyId, startDate, endDate string) *ga.RunReportRequest
+
+// Fetcher runs GA4 report requests. cached reports whether the response was served
+// without calling the API.
+type Fetcher interface {
+	Fetch(ctx context.Context, propertyId, start, end string, request RequestFunc) (response *ga.RunReportResponse, cached bool, err error)
+}
+
+// GA4Fetcher fetches data from Google Analytics
+type GA4Fetcher struct {
+	service *ga.Service
+	cache   *cache.Cache
+}
+
+func NewGA4Fetcher(service *ga.Service) *GA4Fetcher {
+	return &GA4Fetcher{
+		service: service,
+	}
+}
+
+// WithCache puts c in front of the GA4 API for Fetch.
+func (g *GA4Fetcher) WithCache(c *cache.Cache) *GA4Fetcher {
+	g.cache = c
+	return g
+}
+
+// Cached reports whether a response cache is configured.
+func (g *GA4Fetcher) Cached() bool {
+	return g.cache != nil
+}
+
+// pageSize is the number of rows requested per RunReport call when the report does not
+// set a Limit. GA4 returns at most 10,000 rows without one and 250,000 with one.
+const pageSize = 100000
+
+// GetGADataFetcher fetches data from Google Analytics. The report is paged through with
+// Limit and Offset until every one of the RowCount rows GA4 reports has been read; the
+// request's own Limit, when set, is used as the page size.
+func (g *GA4Fetcher) GetGADataFetcher(ctx context.Context, propertyId, start, end string, requestFunc RequestFunc) (*ga.RunReportResponse, error) {
+	// Define the Google Analytics request
+	request := requestFunc(propertyId, start, end)
+	if request.Limit == 0 {
+		request.Limit = pageSize
+	}
+	var response *ga.RunReportResponse
+	for {
+		began := time.Now()
+		page, err := g.service.Properties.RunReport("properties/"+propertyId, request).Context(ctx).Do()
+		metrics.ObserveGA4Request(began, err)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to execute Google Analytics request")
+		}
+		if response == nil {
+			response = page
+		} else {
+			response.Rows = append(response.Rows, page.Rows...)
+			// 마지막 페이지의 quota 가 가장 최근 값입니다.
+			response.PropertyQuota = page.PropertyQuota
+		}
+		if len(page.Rows) == 0 || int64(len(response.Rows)) >= response.RowCount {
+			return response, nil
+		}
+		request.Offset = int64(len(response.Rows))
+	}
+}
+
+// Fetch is GetGADataFetcher with the response cache in front of it. start and end must be
+// resolved YYYY-MM-DD dates so that the cache key identifies the data it covers.
+// cached reports whether the response came from the cache without calling the API.
+func (g *GA4Fetcher) Fetch(ctx context.Context, propertyId, start, end string, requestFunc RequestFunc) (response *ga.RunReportResponse, cached bool, err error) {
+	if g.cache == nil {
+		response, err = g.GetGADataFetcher(ctx, propertyId, start, end, requestFunc)
+		return response, false, err
+	}
+
+	key, err := cache.Key(propertyId, requestFunc(propertyId, start, end))
+	if err != nil {
+		return nil, false, err
+	}
+	if response, ok := g.cache.Get(key); ok {
+		return response, true, nil
+	}
+	response, err = g.GetGADataFetcher(ctx, propertyId, start, end, requestFunc)
+	if err != nil {
+		return nil, false, err
+	}
+	// 캐시 저장 실패는 수집 자체를 실패시키지 않습니다.
+	if err := g.cache.Put(key, end, response); err != nil {
+		logging.FromContext(ctx).Warn("Failed to cache GA4 response", "error", err)
+	}
+	return response, false, nil
+}
+
+// GetMetadata returns the dimensions and metrics available on the property.
+func (g *GA4Fetcher) GetMetadata(ctx context.Context, propertyId string) (*ga.Metadata, error) {
+	metadata, err := g.service.Properties.GetMetadata("properties/" + propertyId + "/metadata").Context(ctx).Do()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get GA4 metadata")
+	}
+	return metadata, nil
+}
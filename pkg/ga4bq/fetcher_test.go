@@ -0,0 +1,51 @@
+package ga4bq
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	ga "google.golang.org/api/analyticsdata/v1beta"
+	"google.golang.org/api/option"
+)
+
+func TestGA4Fetcher_paging(t *testing.T) {
+	const rowCount = 5
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		var req ga.RunReportRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		resp := ga.RunReportResponse{RowCount: rowCount}
+		for i := req.Offset; i < req.Offset+req.Limit && i < rowCount; i++ {
+			resp.Rows = append(resp.Rows, &ga.Row{DimensionValues: []*ga.DimensionValue{{Value: strconv.FormatInt(i, 10)}}})
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	service, err := ga.NewService(context.Background(), option.WithEndpoint(srv.URL), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := func(propertyId, startDate, endDate string) *ga.RunReportRequest {
+		return &ga.RunReportRequest{Limit: 2}
+	}
+	resp, err := NewGA4Fetcher(service).GetGADataFetcher(context.Background(), "123", "2024-01-01", "2024-01-02", request)
+	if err != nil {
+		t.Fatalf("GetGADataFetcher() error = %v", err)
+	}
+	if len(resp.Rows) != rowCount || calls != 3 {
+		t.Fatalf("got %d rows in %d calls, want %d rows in 3", len(resp.Rows), calls, rowCount)
+	}
+	for i, row := range resp.Rows {
+		if got := row.DimensionValues[0].Value; got != strconv.Itoa(i) {
+			t.Errorf("row %d = %s, want rows in order", i, got)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+// Package ga4bq is the public API of go-ga4-to-bigquery: a Pipeline that fetches GA4
+// Data API reports, transforms them and loads them into one or more sinks.
+//
+// The exported identifiers of this package follow semantic versioning: within a major
+// version they are only added to, never removed or changed incompatibly. The aliased
+// types (Config, Report, Sink, ...) are covered by the same promise even though they
+// are defined under internal/.
+package ga4bq
+
+import (
+	"net/http"
+
+	"go-ga4-to-bigquery/internal/config"
+	"go-ga4-to-bigquery/internal/metrics"
+	"go-ga4-to-bigquery/internal/reports"
+	_ "go-ga4-to-bigquery/internal/reports/impl" // registers the built-in reports
+	"go-ga4-to-bigquery/internal/sinks"
+)
+
+// Version is the version of this module, recorded on the BigQuery datasets and tables
+// it creates.
+const Version = "1.0.0"
+
+// Config is the pipeline configuration, the same struct the CLI reads from its config file.
+type Config = config.Config
+
+// Report builds the GA4 request for a report type, transforms its response and
+// describes the destination schema.
+type Report = reports.Report
+
+// Registry maps report types to Report implementations.
+type Registry = reports.Registry
+
+// Metadata describes a registered report.
+type Metadata = reports.Metadata
+
+// ReportFactory returns a new instance of a report.
+type ReportFactory = reports.Factory
+
+// ColumnSourcer is implemented by reports whose columns should be described from GA4
+// metadata; it maps columns to GA4 dimension or metric API names.
+type ColumnSourcer = reports.ColumnSourcer
+
+// Sink loads transformed rows; see Batch for the write protocol.
+type Sink = sinks.Sink
+
+// Batch is one report's load into a Sink: Write, then Commit or Abort.
+type Batch = sinks.Batch
+
+// Destination tells a Sink where and how a report is written.
+type Destination = sinks.Destination
+
+// Planner is implemented by sinks that can describe a load without running it, for dry runs.
+type Planner = sinks.Planner
+
+// Plan describes the effect of loading one report into one sink.
+type Plan = sinks.Plan
+
+// PlannedQuery is a statement a sink would run during a dry run.
+type PlannedQuery = sinks.PlannedQuery
+
+// MetricsHandler serves the pipeline's Prometheus metrics, for programs that run
+// pipelines in a long-lived process and expose their own /metrics endpoint.
+func MetricsHandler() http.Handler {
+	return metrics.Handler()
+}
+
+// LoadConfig reads a config the way the CLI does: file, profile, GA4BQ_* env. It does
+// not validate; call Config.Validate with Registry.Names.
+func LoadConfig(file, profile string) (*Config, error) {
+	cfg, _, err := config.New(config.Options{File: file, Profile: profile})
+	if err != nil {
+		return nil, stageError(StageConfig, err, "failed to load config")
+	}
+	return cfg, nil
+}
+
+// DefaultRegistry returns the registry the built-in reports are registered in.
+func DefaultRegistry() *Registry {
+	return reports.Default
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return reports.NewRegistry()
+}
+
+// RegisterReport adds a report to the default registry.
+func RegisterReport(meta Metadata, factory ReportFactory) error {
+	return reports.Register(meta, factory)
+}
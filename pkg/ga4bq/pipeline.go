@@ -0,0 +1,695 @@
+package ga4bq
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/pkg/errors"
+	ga "google.golang.org/api/analyticsdata/v1beta"
+	"google.golang.org/api/googleapi"
+
+	"go-ga4-to-bigquery/internal/archive"
+	"go-ga4-to-bigquery/internal/auth"
+	"go-ga4-to-bigquery/internal/cache"
+	"go-ga4-to-bigquery/internal/config"
+	"go-ga4-to-bigquery/internal/logging"
+	"go-ga4-to-bigquery/internal/metrics"
+	"go-ga4-to-bigquery/internal/reports"
+	"go-ga4-to-bigquery/internal/sinks"
+	sinkimpl "go-ga4-to-bigquery/internal/sinks/impl"
+)
+
+// Pipeline fetches, transforms and loads the reports of a Config. Parts not supplied
+// through options are built from the config when the pipeline is opened.
+type Pipeline struct {
+	cfg         *Config
+	registry    *Registry
+	fetcher     Fetcher
+	transformer Transformer
+	sinks       map[string]Sink
+	// own 은 Pipeline 이 직접 만든 sink 로, Close 에서 닫습니다.
+	own      map[string]bool
+	bqClient *bigquery.Client
+	// datasetPending 는 dry-run 에서 데이터셋이 아직 없고 CREATE_DATASET 으로 만들어질 때 켜집니다.
+	datasetPending bool
+	runID          string
+	// logger 는 run_id 와 property_id 를 항상 포함합니다.
+	logger *slog.Logger
+	// metadata 는 컬럼 설명에 쓰는 GA4 dimension/metric 정보입니다. GA4 에 연결했을 때만 있습니다.
+	metadata *ga.Metadata
+}
+
+// Option customises a Pipeline.
+type Option func(*Pipeline)
+
+// WithRegistry looks reports up in r instead of DefaultRegistry.
+func WithRegistry(r *Registry) Option {
+	return func(p *Pipeline) { p.registry = r }
+}
+
+// WithLogger logs through l instead of slog.Default. The pipeline adds the run_id,
+// property_id, report and chunk fields.
+func WithLogger(l *slog.Logger) Option {
+	return func(p *Pipeline) { p.logger = l }
+}
+
+// WithFetcher replaces the GA4 Data API fetcher built from the config.
+func WithFetcher(f Fetcher) Option {
+	return func(p *Pipeline) { p.fetcher = f }
+}
+
+// WithTransformer replaces the default transformer.
+func WithTransformer(t Transformer) Option {
+	return func(p *Pipeline) { p.transformer = t }
+}
+
+// WithSink serves the output name with s instead of building it from the config. name
+// may also be a new output; reports use it once it is listed in OUTPUT or REPORT_OUTPUT.
+// The caller keeps ownership of s: Close does not close it.
+func WithSink(name string, s Sink) Option {
+	return func(p *Pipeline) { p.sinks[name] = s }
+}
+
+// New returns a pipeline for cfg. It does not connect to anything; Open does.
+func New(cfg *Config, opts ...Option) (*Pipeline, error) {
+	if cfg == nil {
+		return nil, &StageError{Stage: StageConfig, Err: errors.New("config is nil")}
+	}
+	p := &Pipeline{
+		cfg:      cfg,
+		registry: DefaultRegistry(),
+		sinks:    map[string]Sink{},
+		own:      map[string]bool{},
+		runID:    newRunID(time.Now()),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.transformer == nil {
+		p.transformer = NewGA4Transformer()
+	}
+	if p.logger == nil {
+		p.logger = slog.Default()
+	}
+	p.logger = p.logger.With("run_id", p.runID, "property_id", cfg.PropertyID)
+	return p, nil
+}
+
+// newRunID returns a sortable id that is unique enough to name staging tables.
+func newRunID(now time.Time) string {
+	var b [3]byte
+	_, _ = rand.Read(b[:])
+	return now.UTC().Format("20060102T150405") + "_" + hex.EncodeToString(b[:])
+}
+
+// RunID identifies this pipeline's run.
+func (p *Pipeline) RunID() string {
+	return p.runID
+}
+
+// Registry returns the registry the pipeline looks reports up in.
+func (p *Pipeline) Registry() *Registry {
+	return p.registry
+}
+
+// Open builds the GA4 fetcher and the sinks and checks that both sides are reachable
+// with the configured credentials before any report runs. It is safe to call again.
+func (p *Pipeline) Open(ctx context.Context) error {
+	if p.fetcher == nil {
+		if err := p.openGA4(ctx); err != nil {
+			return err
+		}
+	}
+	return p.OpenSinks(ctx)
+}
+
+func (p *Pipeline) openGA4(ctx context.Context) error {
+	gaOpts, err := auth.ClientOptions(ctx, p.cfg, p.cfg.GA4Auth(), auth.GA4Scope)
+	if err != nil {
+		return stageError(StageAuth, err, "failed to set up GA4 credentials")
+	}
+
+	// Create a new Google Analytics Data service
+	gaService, err := ga.NewService(ctx, gaOpts...)
+	if err != nil {
+		return stageError(StageAuth, err, "failed to create Google Analytics service")
+	}
+	fetcher := NewGA4Fetcher(gaService)
+	if p.cfg.Cache.Dir != "" {
+		fetcher.WithCache(cache.New(p.cfg.Cache.Dir, cache.Policy{
+			TTL:         p.cfg.Cache.TTL,
+			StableAfter: p.cfg.Cache.StableAfterDays,
+		}))
+	}
+
+	// 조회나 적재 전에 권한 문제를 드러내기 위해 가벼운 metadata 호출을 합니다.
+	metadata, err := fetcher.GetMetadata(ctx, p.cfg.PropertyID)
+	if err != nil {
+		return stageError(StageAuth, err, "cannot read GA4 property "+p.cfg.PropertyID)
+	}
+	p.fetcher = fetcher
+	p.metadata = metadata
+	return nil
+}
+
+// OpenSinks builds every configured sink not supplied with WithSink. When BigQuery is
+// an output, the dataset is checked up front. Replay only needs the sinks.
+func (p *Pipeline) OpenSinks(ctx context.Context) error {
+	for _, name := range p.outputs() {
+		if _, ok := p.sinks[name]; ok {
+			continue
+		}
+		if name == config.OutputBigQuery {
+			if err := p.openBigQuery(ctx, true); err != nil {
+				return err
+			}
+		}
+		sink, err := p.newSink(ctx, name)
+		if err != nil {
+			return stageError(StageConfig, err, "failed to create sink "+name)
+		}
+		p.sinks[name] = sink
+		p.own[name] = true
+	}
+	return nil
+}
+
+// outputs returns every output used by any configured report, in config.Outputs order
+// followed by outputs only known through WithSink.
+func (p *Pipeline) outputs() []string {
+	var names []string
+	seen := map[string]bool{}
+	add := func(name string) {
+		if !seen[name] && p.cfg.HasOutput(name) {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	for _, name := range config.Outputs {
+		add(name)
+	}
+	for name := range p.sinks {
+		add(name)
+	}
+	return names
+}
+
+// openBigQuery creates the client and checks the dataset. A missing dataset is created
+// when CREATE_DATASET is set, unless create is false as in a dry-run. An existing dataset
+// must be in DATASET_LOCATION when it is set, since tables always follow their dataset.
+func (p *Pipeline) openBigQuery(ctx context.Context, create bool) error {
+	// GA4 와 BigQuery 는 서로 다른 자격 증명을 사용할 수 있습니다.
+	bqOpts, err := auth.ClientOptions(ctx, p.cfg, p.cfg.BigQueryAuth(), auth.BigQueryScope)
+	if err != nil {
+		return stageError(StageAuth, err, "failed to set up BigQuery credentials")
+	}
+
+	// Create a new BigQuery client
+	bqClient, err := bigquery.NewClient(ctx, p.cfg.ProjectId, bqOpts...)
+	if err != nil {
+		return stageError(StageAuth, err, "failed to create BigQuery client")
+	}
+	p.bqClient = bqClient
+
+	dataset := p.bqClient.Dataset(p.cfg.DatasetID)
+	md, err := dataset.Metadata(ctx)
+	if err != nil && p.cfg.CreateDataset && isNotFound(err) {
+		if !create {
+			p.logger.Info("BigQuery dataset does not exist and would be created", "dataset", p.cfg.DatasetID)
+			p.datasetPending = true
+			return nil
+		}
+		return p.createDataset(ctx, dataset)
+	}
+	if err != nil {
+		return stageError(StageAuth, err, "cannot access BigQuery dataset "+p.cfg.DatasetID)
+	}
+	if err := checkLocation(md, p.cfg.DatasetLocation); err != nil {
+		return &StageError{Stage: StageConfig, Err: err}
+	}
+	return nil
+}
+
+// checkLocation fails when DATASET_LOCATION names a location other than the dataset's.
+func checkLocation(md *bigquery.DatasetMetadata, location string) error {
+	if location != "" && !strings.EqualFold(md.Location, location) {
+		return errors.Errorf("dataset %s is in %s, not DATASET_LOCATION %s", md.FullID, md.Location, location)
+	}
+	return nil
+}
+
+func (p *Pipeline) createDataset(ctx context.Context, dataset *bigquery.Dataset) error {
+	location := p.cfg.DatasetLocation
+	if location == "" {
+		location = "US"
+	}
+	if err := dataset.Create(ctx, &bigquery.DatasetMetadata{
+		Location:               location,
+		DefaultTableExpiration: p.cfg.DefaultTableExpiration,
+		Labels:                 p.labels(),
+	}); err != nil {
+		return stageError(StageAuth, err, "failed to create BigQuery dataset "+p.cfg.DatasetID)
+	}
+	p.logger.Info("Created BigQuery dataset", "dataset", p.cfg.DatasetID, "location", location)
+	return nil
+}
+
+// labels are set on every dataset and table the pipeline creates.
+func (p *Pipeline) labels() map[string]string {
+	return map[string]string{"tool_version": sinkimpl.LabelValue(Version)}
+}
+
+func isNotFound(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound
+}
+
+func (p *Pipeline) newSink(ctx context.Context, name string) (Sink, error) {
+	switch name {
+	case config.OutputBigQuery:
+		return p.bigQuerySink(), nil
+	case config.OutputCSV:
+		return sinkimpl.NewCsvSink(p.cfg.OutDir), nil
+	case config.OutputParquet:
+		return sinkimpl.NewParquetSink(p.cfg.OutDir), nil
+	case config.OutputNdjson:
+		return sinkimpl.NewNdjsonSink(p.cfg.OutDir, p.cfg.Compression), nil
+	case config.OutputAvro:
+		return sinkimpl.NewAvroSink(p.cfg.OutDir, p.cfg.Compression), nil
+	case config.OutputPostgres:
+		return sinkimpl.NewPostgresSink(ctx, p.cfg.PostgresDSN, p.cfg.PostgresSchema)
+	case config.OutputSQLite, config.OutputDuckDB:
+		return sinkimpl.NewLocalDBSink(name, p.cfg.DBPath)
+	case config.OutputWebhook:
+		maxRetries := sinkimpl.DefaultWebhookRetries
+		if p.cfg.Webhook.MaxRetries != nil {
+			maxRetries = *p.cfg.Webhook.MaxRetries
+		}
+		return sinkimpl.NewWebhookSink(sinkimpl.WebhookOptions{
+			URL:        p.cfg.Webhook.URL,
+			BatchSize:  p.cfg.Webhook.BatchSize,
+			Headers:    p.cfg.Webhook.Headers,
+			HMACSecret: p.cfg.Webhook.HMACSecret,
+			MaxRetries: maxRetries,
+		}), nil
+	default:
+		return nil, errors.Errorf("invalid output %q", name)
+	}
+}
+
+func (p *Pipeline) bigQuerySink() *sinkimpl.BigQuerySink {
+	return sinkimpl.NewBigQuerySink(p.bqClient, p.cfg.DatasetID).
+		WithAllowBreaking(p.cfg.AllowBreaking).
+		WithLabels(p.labels())
+}
+
+// sinkFor fans out to every output configured for the report type.
+func (p *Pipeline) sinkFor(reportType string) Sink {
+	var selected []Sink
+	for _, name := range p.cfg.OutputsFor(reportType) {
+		selected = append(selected, p.sinks[name])
+	}
+	return sinks.Multi(selected...)
+}
+
+// Close releases the clients and sinks the pipeline created.
+func (p *Pipeline) Close() error {
+	for name, sink := range p.sinks {
+		if !p.own[name] {
+			continue
+		}
+		if c, ok := sink.(io.Closer); ok {
+			if err := c.Close(); err != nil {
+				p.logger.Warn("Failed to close sink", "sink", name, "error", err)
+			}
+		}
+	}
+	if p.bqClient != nil {
+		return p.bqClient.Close()
+	}
+	return nil
+}
+
+// cached reports whether the fetcher has a response cache; see GA4Fetcher.Cached.
+func (p *Pipeline) cached() bool {
+	c, ok := p.fetcher.(interface{ Cached() bool })
+	return ok && c.Cached()
+}
+
+// ReportSummary is the outcome of one report in a run.
+type ReportSummary struct {
+	ReportType string
+	Rows       int
+	Cached     bool
+	// Reconciliation is set when RECONCILE is enabled for the report.
+	Reconciliation []ReconcileResult
+	Err            error
+}
+
+// Summary is the outcome of Run or Replay.
+type Summary struct {
+	Reports []ReportSummary
+	// CacheEnabled is set when the fetcher has a response cache.
+	CacheEnabled bool
+}
+
+// Run fetches and loads every configured report, opening the pipeline first if needed.
+// The summary covers the reports processed before a failure as well.
+func (p *Pipeline) Run(ctx context.Context) (*Summary, error) {
+	if err := p.Open(ctx); err != nil {
+		return nil, err
+	}
+	summary := &Summary{CacheEnabled: p.cached()}
+	for _, reportType := range p.cfg.ReportTypes {
+		s, err := p.RunReport(ctx, reportType)
+		summary.Reports = append(summary.Reports, s)
+		if err != nil {
+			return summary, errors.WithMessagef(err, "failed to run report %s", reportType)
+		}
+	}
+	return summary, nil
+}
+
+// RunReport fetches one report type and loads it into its sinks. The pipeline must be open.
+// With a BigQuery output, the execution is recorded in RunsTable whether it succeeds or not.
+func (p *Pipeline) RunReport(ctx context.Context, reportType string) (ReportSummary, error) {
+	rec := &runRecord{
+		runID:      p.runID,
+		propertyID: p.cfg.PropertyID,
+		reportType: reportType,
+		startDate:  p.cfg.InitialFetchFromDate,
+		endDate:    p.cfg.FetchToDate,
+		startedAt:  time.Now(),
+	}
+	s, err := p.runReport(ctx, reportType, rec)
+	if err == nil {
+		metrics.LastSuccess.WithLabelValues(p.cfg.PropertyID, reportType).SetToCurrentTime()
+	}
+	if p.audited() {
+		rec.duration = time.Since(rec.startedAt)
+		rec.err = err
+		// 실행이 취소되어도 감사 기록은 남깁니다.
+		p.recordRun(context.WithoutCancel(ctx), rec)
+	}
+	return s, err
+}
+
+func (p *Pipeline) runReport(ctx context.Context, reportType string, rec *runRecord) (ReportSummary, error) {
+	s := ReportSummary{ReportType: reportType}
+	logger := p.logger.With("report", reportType)
+	ctx = logging.NewContext(ctx, logger)
+	report, err := p.registry.New(reportType)
+	if err != nil {
+		s.Err = stageError(StageConfig, err, "failed to select report")
+		return s, s.Err
+	}
+	// 보관, 적재, 대조가 모두 같은 테이블을 가리키도록 목적지를 한 번만 정합니다.
+	dest := p.destination(reportType, report, p.cfg.InitialFetchFromDate, p.cfg.FetchToDate)
+	rec.table = dest.Table
+	if resolvedStart, resolvedEnd, err := p.resolvedRange(); err == nil {
+		rec.startDate, rec.endDate = resolvedStart, resolvedEnd
+	}
+
+	start, end, err := p.requestRange()
+	if err != nil {
+		s.Err = stageError(StageConfig, err, "failed to resolve date range")
+		return s, s.Err
+	}
+	request := p.requestFunc(reportType, report)
+	if p.audited() {
+		if rec.requestHash, err = cache.Key(p.cfg.PropertyID, request(p.cfg.PropertyID, start, end)); err != nil {
+			s.Err = stageError(StageFetch, err, "failed to hash request")
+			return s, s.Err
+		}
+	}
+
+	// Get the data from Google Analytics
+	result, cached, err := p.fetcher.Fetch(ctx, p.cfg.PropertyID, start, end, request)
+	if err != nil {
+		s.Err = stageError(StageFetch, err, "failed to get GA data")
+		return s, s.Err
+	}
+	s.Cached = cached
+	logger.Info("Fetched GA4 report", "rows", len(result.Rows), "row_count", result.RowCount, "cached", cached)
+	rec.rowsFetched = len(result.Rows)
+	metrics.Rows.WithLabelValues(p.cfg.PropertyID, reportType, metrics.StageFetched).Add(float64(len(result.Rows)))
+	if !cached {
+		rec.quotaTokens = quotaTokens(result)
+		metrics.ObserveQuota(p.cfg.PropertyID, result.PropertyQuota)
+	}
+
+	if p.cfg.ArchiveDir != "" && !cached {
+		entry, err := p.archiveEntry(reportType, dest.Table)
+		if err != nil {
+			s.Err = stageError(StageConfig, err, "failed to resolve archive key")
+			return s, s.Err
+		}
+		if err := archive.NewStore(p.cfg.ArchiveDir).Save(entry, result); err != nil {
+			s.Err = stageError(StageFetch, err, "failed to archive GA4 response")
+			return s, s.Err
+		}
+	}
+
+	if rec.rowsLoaded, err = p.load(ctx, reportType, report, result, dest); err != nil {
+		s.Err = err
+		return s, err
+	}
+	s.Rows = len(result.Rows)
+
+	if p.reconciled(reportType) {
+		if s.Reconciliation, err = p.reconcile(ctx, report, result, dest); err != nil {
+			s.Err = stageError(StageReconcile, err, "BigQuery does not match GA4")
+			return s, s.Err
+		}
+	}
+	return s, nil
+}
+
+// resolvedRange returns the configured date range with relative dates resolved.
+// requestRange is the date range sent to GA4. Relative dates are resolved first when
+// responses are cached, so that the cache key names the days the response covers. A
+// dry-run that has not opened GA4 goes by CACHE.DIR.
+func (p *Pipeline) requestRange() (start, end string, err error) {
+	if p.cached() || (p.fetcher == nil && p.cfg.Cache.Dir != "") {
+		// 캐시 키가 실제 날짜를 가리키도록 상대 날짜를 먼저 풀어서 요청합니다.
+		return p.resolvedRange()
+	}
+	return p.cfg.InitialFetchFromDate, p.cfg.FetchToDate, nil
+}
+
+// requestFunc is the report's request as Run sends it: with the TOTAL aggregation when
+// the report is reconciled, and always asking for the property quota.
+func (p *Pipeline) requestFunc(reportType string, report Report) RequestFunc {
+	request := report.ReportRequestFunc
+	if p.reconciled(reportType) {
+		request = withTotals(request)
+	}
+	// 남은 quota 는 감사 기록과 metrics 에 모두 쓰입니다.
+	return withQuota(request)
+}
+
+func (p *Pipeline) resolvedRange() (start, end string, err error) {
+	now := time.Now()
+	if start, err = config.ResolveDate(p.cfg.InitialFetchFromDate, now); err != nil {
+		return "", "", err
+	}
+	if end, err = config.ResolveDate(p.cfg.FetchToDate, now); err != nil {
+		return "", "", err
+	}
+	return start, end, nil
+}
+
+// archiveEntry keys the configured date range with relative dates resolved, so that
+// a later replay finds the response under the dates it actually covered, and records
+// the table it is loaded into so that the replay writes to the same one.
+func (p *Pipeline) archiveEntry(reportType, table string) (archive.Entry, error) {
+	start, end, err := p.resolvedRange()
+	if err != nil {
+		return archive.Entry{}, err
+	}
+	return archive.Entry{PropertyID: p.cfg.PropertyID, ReportType: reportType, StartDate: start, EndDate: end, Table: table}, nil
+}
+
+// load transforms the response and writes it to every sink of the report, returning the rows written.
+func (p *Pipeline) load(ctx context.Context, reportType string, report Report, result *ga.RunReportResponse, dest Destination) (int, error) {
+	logger := logging.FromContext(ctx).With("chunk", dest.StartDate+".."+dest.EndDate)
+	ctx = logging.NewContext(ctx, logger)
+
+	// Transform the data
+	transformedData, err := p.transformer.TransformData(result, report.TransformFunc)
+	if err != nil {
+		return 0, stageError(StageTransform, err, "failed to transform data")
+	}
+	metrics.Rows.WithLabelValues(p.cfg.PropertyID, reportType, metrics.StageTransformed).Add(float64(len(transformedData)))
+	// 행 데이터는 양이 많으므로 debug 레벨에서만 남깁니다.
+	logger.Debug("Transformed data", "rows", len(transformedData), "data", transformedData)
+
+	// Load the data into every configured sink
+	dest.ExpectedRows = result.RowCount
+	if err := sinks.Write(ctx, p.sinkFor(reportType), dest, transformedData); err != nil {
+		return 0, stageError(StageLoad, err, "failed to load data")
+	}
+	metrics.Rows.WithLabelValues(p.cfg.PropertyID, reportType, metrics.StageLoaded).Add(float64(len(transformedData)))
+	return len(transformedData), nil
+}
+
+// tableName is the table reportType loads for day, named after its registered DefaultTable.
+func (p *Pipeline) tableName(reportType string, report Report, day time.Time) string {
+	meta, _ := p.registry.Metadata(reportType)
+	return meta.TableName(p.cfg.TablePrefix, report, day)
+}
+
+func (p *Pipeline) keyedTableName(reportType string, report Report) string {
+	meta, _ := p.registry.Metadata(reportType)
+	return meta.KeyedTableName(p.cfg.TablePrefix, report)
+}
+
+func (p *Pipeline) destination(reportType string, report Report, start, end string) Destination {
+	return Destination{
+		PropertyID:   p.cfg.PropertyID,
+		ReportType:   reportType,
+		Table:        p.tableName(reportType, report, time.Now()),
+		KeyedTable:   p.keyedTableName(reportType, report),
+		Schema:       p.describedSchema(report),
+		Key:          report.NaturalKey(),
+		StartDate:    start,
+		EndDate:      end,
+		RunID:        p.runID,
+		FullRefresh:  p.cfg.FullRefreshFor(reportType),
+		ExpectedRows: -1,
+	}
+}
+
+// describedSchema returns the report schema with column descriptions from GA4 metadata,
+// "<UI name>: <description>", for reports that say which GA4 field each column holds.
+func (p *Pipeline) describedSchema(report Report) bigquery.Schema {
+	schema := report.Schema()
+	sourcer, ok := report.(reports.ColumnSourcer)
+	if !ok || p.metadata == nil {
+		return schema
+	}
+	descriptions := map[string]string{}
+	for _, d := range p.metadata.Dimensions {
+		descriptions[d.ApiName] = fieldDescription(d.UiName, d.Description)
+	}
+	for _, m := range p.metadata.Metrics {
+		descriptions[m.ApiName] = fieldDescription(m.UiName, m.Description)
+	}
+
+	sources := sourcer.ColumnSources()
+	described := make(bigquery.Schema, len(schema))
+	for i, f := range schema {
+		copied := *f
+		if d, ok := descriptions[sources[f.Name]]; ok && copied.Description == "" {
+			copied.Description = d
+		}
+		described[i] = &copied
+	}
+	return described
+}
+
+// fieldDescription joins a GA4 UI name and description, within BigQuery's 1024 character limit.
+func fieldDescription(uiName, description string) string {
+	d := uiName
+	if description != "" {
+		d += ": " + description
+	}
+	if len(d) > 1024 {
+		d = d[:1024]
+	}
+	return d
+}
+
+// Replay re-runs the transform and load stages for every archived response of the
+// configured reports, without calling GA4. Only the sinks are opened.
+func (p *Pipeline) Replay(ctx context.Context) (*Summary, error) {
+	if p.cfg.ArchiveDir == "" {
+		return nil, &StageError{Stage: StageConfig, Err: errors.New("ARCHIVE_DIR is required for replay")}
+	}
+	if err := p.OpenSinks(ctx); err != nil {
+		return nil, err
+	}
+	summary := &Summary{}
+	store := archive.NewStore(p.cfg.ArchiveDir)
+	for _, reportType := range p.cfg.ReportTypes {
+		report, err := p.registry.New(reportType)
+		if err != nil {
+			return summary, stageError(StageConfig, err, "failed to select report")
+		}
+		logger := p.logger.With("report", reportType)
+		reportCtx := logging.NewContext(ctx, logger)
+		entries, err := store.List(p.cfg.PropertyID, reportType)
+		if err != nil {
+			return summary, stageError(StageFetch, err, "failed to list archive")
+		}
+		if len(entries) == 0 {
+			logger.Warn("No archived responses")
+		}
+		for _, group := range replayGroups(entries) {
+			first, last := group[0], group[len(group)-1]
+			if first.Table == "" {
+				return summary, &StageError{Stage: StageConfig, Err: errors.Errorf(
+					"archived response %s..%s does not record its table; fetch the range again to replay it", first.StartDate, first.EndDate)}
+			}
+			result, err := loadArchived(store, group)
+			if err != nil {
+				return summary, stageError(StageFetch, err, "failed to load archived response")
+			}
+			logger.Info("Replaying archived responses", "table", first.Table, "responses", len(group))
+			s := ReportSummary{ReportType: reportType, Rows: len(result.Rows)}
+			// 원래 실행이 적재한 테이블을 그 테이블에 들어간 응답 전체로 교체합니다.
+			dest := p.destination(reportType, report, first.StartDate, last.EndDate)
+			dest.Table = first.Table
+			dest.FullRefresh = true
+			if _, err := p.load(reportCtx, reportType, report, result, dest); err != nil {
+				s.Rows, s.Err = 0, err
+				summary.Reports = append(summary.Reports, s)
+				return summary, errors.WithMessagef(err, "failed to replay %s into %s", reportType, first.Table)
+			}
+			summary.Reports = append(summary.Reports, s)
+		}
+	}
+	return summary, nil
+}
+
+// replayGroups groups entries by the table they were loaded into, in archive order, so
+// that each table is replaced once with every response that went into it.
+func replayGroups(entries []archive.Entry) [][]archive.Entry {
+	var groups [][]archive.Entry
+	index := map[string]int{}
+	for _, e := range entries {
+		i, ok := index[e.Table]
+		if !ok || e.Table == "" {
+			index[e.Table] = len(groups)
+			groups = append(groups, []archive.Entry{e})
+			continue
+		}
+		groups[i] = append(groups[i], e)
+	}
+	return groups
+}
+
+// loadArchived reads the responses of group as one response with all of their rows.
+func loadArchived(store *archive.Store, group []archive.Entry) (*ga.RunReportResponse, error) {
+	var merged *ga.RunReportResponse
+	for _, entry := range group {
+		resp, err := store.Load(entry)
+		if err != nil {
+			return nil, err
+		}
+		if merged == nil {
+			merged = resp
+			continue
+		}
+		merged.Rows = append(merged.Rows, resp.Rows...)
+		merged.RowCount += resp.RowCount
+	}
+	return merged, nil
+}
@@ -0,0 +1,325 @@
+package ga4bq
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/pkg/errors"
+	ga "google.golang.org/api/analyticsdata/v1beta"
+
+	"go-ga4-to-bigquery/internal/archive"
+	"go-ga4-to-bigquery/internal/logging"
+)
+
+type fakeFetcher struct {
+	err error
+}
+
+func (f fakeFetcher) Fetch(ctx context.Context, propertyId, start, end string, request RequestFunc) (*ga.RunReportResponse, bool, error) {
+	if f.err != nil {
+		return nil, false, f.err
+	}
+	return &ga.RunReportResponse{Rows: []*ga.Row{
+		{DimensionValues: []*ga.DimensionValue{{Value: "20240101"}}},
+		{DimensionValues: []*ga.DimensionValue{{Value: "20240102"}}},
+	}}, false, nil
+}
+
+type fakeRow map[string]bigquery.Value
+
+func (r fakeRow) Save() (map[string]bigquery.Value, string, error) {
+	return r, bigquery.NoDedupeID, nil
+}
+
+type fakeReport struct{}
+
+func (fakeReport) ReportRequestFunc(propertyId, startDate, endDate string) *ga.RunReportRequest {
+	return &ga.RunReportRequest{}
+}
+func (fakeReport) TransformFunc(result *ga.RunReportResponse) ([]bigquery.ValueSaver, error) {
+	var rows []bigquery.ValueSaver
+	for _, r := range result.Rows {
+		rows = append(rows, fakeRow{"date": r.DimensionValues[0].Value})
+	}
+	return rows, nil
+}
+func (fakeReport) Schema() bigquery.Schema {
+	return bigquery.Schema{{Name: "date", Type: bigquery.StringFieldType}}
+}
+func (fakeReport) NaturalKey() []string { return []string{"date"} }
+func (fakeReport) ReportTitle() string  { return "fake" }
+
+type memorySink struct {
+	dest      Destination
+	committed []bigquery.ValueSaver
+}
+
+type memoryBatch struct {
+	sink *memorySink
+	rows []bigquery.ValueSaver
+}
+
+func (s *memorySink) Name() string { return "memory" }
+
+func (s *memorySink) Prepare(ctx context.Context, dest Destination) (Batch, error) {
+	s.dest = dest
+	return &memoryBatch{sink: s}, nil
+}
+
+func (b *memoryBatch) Write(ctx context.Context, rows []bigquery.ValueSaver) error {
+	b.rows = append(b.rows, rows...)
+	return nil
+}
+
+func (b *memoryBatch) Commit(ctx context.Context) error {
+	b.sink.committed = b.rows
+	return nil
+}
+
+func (b *memoryBatch) Abort(ctx context.Context) error { return nil }
+
+func TestPipeline_Run(t *testing.T) {
+	registry := NewRegistry()
+	registry.MustRegister(Metadata{Name: "fake"}, func() Report { return fakeReport{} })
+
+	tests := []struct {
+		name     string
+		fetchErr error
+		wantRows int
+		wantCode int
+	}{
+		{name: "loads", wantRows: 2},
+		{name: "fetch error", fetchErr: errors.New("quota"), wantCode: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := &memorySink{}
+			cfg := &Config{PropertyID: "123", TablePrefix: "t_", ReportTypes: []string{"fake"}, Output: []string{"memory"}}
+			p, err := New(cfg, WithRegistry(registry), WithFetcher(fakeFetcher{err: tt.fetchErr}), WithSink("memory", sink))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer p.Close()
+
+			summary, err := p.Run(context.Background())
+			if got := ExitCode(err); got != tt.wantCode {
+				t.Fatalf("Run() error = %v, exit code %d, want %d", err, got, tt.wantCode)
+			}
+			if len(summary.Reports) != 1 || summary.Reports[0].Rows != tt.wantRows {
+				t.Errorf("summary = %+v, want one report with %d rows", summary.Reports, tt.wantRows)
+			}
+			if len(sink.committed) != tt.wantRows {
+				t.Errorf("committed %d rows, want %d", len(sink.committed), tt.wantRows)
+			}
+			if tt.wantRows > 0 && sink.dest.Table != "t_fake" {
+				t.Errorf("Destination.Table = %q, want t_fake", sink.dest.Table)
+			}
+		})
+	}
+}
+
+func TestPipeline_Replay(t *testing.T) {
+	registry := NewRegistry()
+	registry.MustRegister(Metadata{Name: "fake", DefaultTable: "fake"}, func() Report { return fakeReport{} })
+	resp, _, _ := fakeFetcher{}.Fetch(context.Background(), "123", "", "", nil)
+
+	tests := []struct {
+		name      string
+		entries   []archive.Entry
+		wantTable string
+		wantRows  int
+		wantErr   bool
+	}{
+		{
+			name: "responses of one table",
+			entries: []archive.Entry{
+				{StartDate: "2024-01-01", EndDate: "2024-01-01", Table: "t_fake_20240103"},
+				{StartDate: "2024-01-02", EndDate: "2024-01-02", Table: "t_fake_20240103"},
+			},
+			wantTable: "t_fake_20240103",
+			wantRows:  4,
+		},
+		{
+			name:    "no recorded table",
+			entries: []archive.Entry{{StartDate: "2024-01-01", EndDate: "2024-01-02"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, e := range tt.entries {
+				e.PropertyID, e.ReportType = "123", "fake"
+				if err := archive.NewStore(dir).Save(e, resp); err != nil {
+					t.Fatal(err)
+				}
+			}
+			sink := &memorySink{}
+			cfg := &Config{PropertyID: "123", TablePrefix: "t_", ReportTypes: []string{"fake"}, Output: []string{"memory"}, ArchiveDir: dir}
+			p, err := New(cfg, WithRegistry(registry), WithSink("memory", sink))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer p.Close()
+
+			if _, err := p.Replay(context.Background()); (err != nil) != tt.wantErr {
+				t.Fatalf("Replay() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if sink.dest.Table != tt.wantTable || !sink.dest.FullRefresh {
+				t.Errorf("Destination = %s, full refresh %v, want %s replaced", sink.dest.Table, sink.dest.FullRefresh, tt.wantTable)
+			}
+			if len(sink.committed) != tt.wantRows {
+				t.Errorf("committed %d rows, want %d", len(sink.committed), tt.wantRows)
+			}
+		})
+	}
+}
+
+func TestPipeline_RunLogging(t *testing.T) {
+	registry := NewRegistry()
+	registry.MustRegister(Metadata{Name: "fake"}, func() Report { return fakeReport{} })
+
+	tests := []struct {
+		name     string
+		level    string
+		wantRows bool
+	}{
+		{name: "info hides rows", level: "info", wantRows: false},
+		{name: "debug logs rows", level: "debug", wantRows: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger, err := logging.New(&buf, logging.FormatJSON, tt.level)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg := &Config{PropertyID: "123", ReportTypes: []string{"fake"}, Output: []string{"memory"},
+				InitialFetchFromDate: "2024-01-01", FetchToDate: "2024-01-02"}
+			p, err := New(cfg, WithRegistry(registry), WithFetcher(fakeFetcher{}), WithSink("memory", &memorySink{}), WithLogger(logger))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer p.Close()
+			if _, err := p.Run(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			out := buf.String()
+			if got := strings.Contains(out, "Transformed data"); got != tt.wantRows {
+				t.Errorf("rows logged = %v, want %v:\n%s", got, tt.wantRows, out)
+			}
+			for _, field := range []string{`"run_id":"` + p.RunID() + `"`, `"property_id":"123"`, `"report":"fake"`} {
+				if !strings.Contains(out, field) {
+					t.Errorf("log is missing %s:\n%s", field, out)
+				}
+			}
+			if tt.wantRows && !strings.Contains(out, `"chunk":"2024-01-01..2024-01-02"`) {
+				t.Errorf("row log is missing the chunk:\n%s", out)
+			}
+		})
+	}
+}
+
+type describedReport struct{ fakeReport }
+
+func (describedReport) ColumnSources() map[string]string {
+	return map[string]string{"date": "date"}
+}
+
+func TestPipeline_describedSchema(t *testing.T) {
+	p, err := New(&Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.metadata = &ga.Metadata{Dimensions: []*ga.DimensionMetadata{
+		{ApiName: "date", UiName: "Date", Description: "The date of the event, formatted as YYYYMMDD."},
+	}}
+
+	tests := []struct {
+		name   string
+		report Report
+		want   string
+	}{
+		{name: "with sources", report: describedReport{}, want: "Date: The date of the event, formatted as YYYYMMDD."},
+		{name: "without sources", report: fakeReport{}, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema := p.describedSchema(tt.report)
+			if got := schema[0].Description; got != tt.want {
+				t.Errorf("Description = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckLocation(t *testing.T) {
+	md := &bigquery.DatasetMetadata{FullID: "proj:ds", Location: "asia-northeast3"}
+	tests := []struct {
+		name     string
+		location string
+		wantErr  bool
+	}{
+		{name: "unset", location: ""},
+		{name: "same", location: "ASIA-NORTHEAST3"},
+		{name: "other", location: "US", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkLocation(md, tt.location); (err != nil) != tt.wantErr {
+				t.Errorf("checkLocation() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+type dateRangeReport struct{ fakeReport }
+
+func (dateRangeReport) ReportRequestFunc(propertyId, startDate, endDate string) *ga.RunReportRequest {
+	return &ga.RunReportRequest{DateRanges: []*ga.DateRange{{StartDate: startDate, EndDate: endDate}}}
+}
+
+func TestPipeline_DryRunRequest(t *testing.T) {
+	registry := NewRegistry()
+	registry.MustRegister(Metadata{Name: "fake"}, func() Report { return dateRangeReport{} })
+	tests := []struct {
+		name      string
+		cacheDir  string
+		wantStart string
+	}{
+		{name: "uncached", wantStart: "yesterday"},
+		{name: "cached resolves dates", cacheDir: t.TempDir(), wantStart: time.Now().AddDate(0, 0, -1).Format("2006-01-02")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{PropertyID: "123", ReportTypes: []string{"fake"}, Output: []string{"memory"},
+				InitialFetchFromDate: "yesterday", FetchToDate: "today"}
+			cfg.Cache.Dir = tt.cacheDir
+			p, err := New(cfg, WithRegistry(registry), WithSink("memory", &memorySink{}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer p.Close()
+
+			reports, err := p.DryRun(context.Background(), false)
+			if err != nil {
+				t.Fatalf("DryRun() error = %v", err)
+			}
+			request := reports[0].Request
+			if !request.ReturnPropertyQuota {
+				t.Error("dry-run request does not ask for the property quota as Run does")
+			}
+			if got := request.DateRanges[0].StartDate; got != tt.wantStart {
+				t.Errorf("StartDate = %s, want %s", got, tt.wantStart)
+			}
+		})
+	}
+}
@@ -0,0 +1,306 @@
+package ga4bq
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/pkg/errors"
+	ga "google.golang.org/api/analyticsdata/v1beta"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iterator"
+
+	"go-ga4-to-bigquery/internal/config"
+	"go-ga4-to-bigquery/internal/reports"
+)
+
+// ReconcileTable is the dataset table every reconciliation result is recorded in.
+const ReconcileTable = "_reconciliation"
+
+// RowCountMetric is the Metric of the row count comparison.
+const RowCountMetric = "row_count"
+
+// ReconcileResult compares one GA4 total with the matching BigQuery aggregate.
+type ReconcileResult struct {
+	Metric   string
+	Column   string
+	GA4      float64
+	BigQuery float64
+	// Difference is |BigQuery - GA4| relative to GA4.
+	Difference float64
+	OK         bool
+}
+
+// AdditiveMetrics are the GA4 metrics reconciled when RECONCILE.METRICS is empty. Their
+// rows add up to the GA4 total; user counts, ratios and averages do not.
+var AdditiveMetrics = []string{
+	"eventCount",
+	"eventValue",
+	"sessions",
+	"engagedSessions",
+	"screenPageViews",
+	"userEngagementDuration",
+	"conversions",
+	"keyEvents",
+	"transactions",
+	"ecommercePurchases",
+	"purchaseRevenue",
+	"totalRevenue",
+}
+
+// reconciled reports whether the report's BigQuery load is checked against GA4 totals.
+// Appended tables are compared over the loaded date range only; see aggregate.
+func (p *Pipeline) reconciled(reportType string) bool {
+	if !p.cfg.Reconcile.Enabled {
+		return false
+	}
+	for _, o := range p.cfg.OutputsFor(reportType) {
+		if o == config.OutputBigQuery {
+			return true
+		}
+	}
+	return false
+}
+
+// withTotals asks GA4 for the TOTAL aggregation next to the rows.
+func withTotals(request RequestFunc) RequestFunc {
+	return func(propertyId, startDate, endDate string) *ga.RunReportRequest {
+		r := request(propertyId, startDate, endDate)
+		for _, a := range r.MetricAggregations {
+			if a == "TOTAL" {
+				return r
+			}
+		}
+		r.MetricAggregations = append(r.MetricAggregations, "TOTAL")
+		return r
+	}
+}
+
+// reconcileColumn is a metric column and the total GA4 reported for it.
+type reconcileColumn struct {
+	metric string
+	column string
+	total  float64
+}
+
+// reconcileColumns picks the numeric metric columns to compare: those of metrics, or of
+// AdditiveMetrics when metrics is empty.
+func reconcileColumns(report Report, result *ga.RunReportResponse, metrics []string) ([]reconcileColumn, error) {
+	sourcer, ok := report.(reports.ColumnSourcer)
+	if !ok || len(result.Totals) == 0 {
+		return nil, nil
+	}
+	columns := map[string]string{}
+	for column, source := range sourcer.ColumnSources() {
+		columns[source] = column
+	}
+	types := map[string]bigquery.FieldType{}
+	for _, f := range report.Schema() {
+		types[f.Name] = f.Type
+	}
+	if len(metrics) == 0 {
+		metrics = AdditiveMetrics
+	}
+	wanted := map[string]bool{}
+	for _, m := range metrics {
+		wanted[m] = true
+	}
+
+	var out []reconcileColumn
+	for i, h := range result.MetricHeaders {
+		column, ok := columns[h.Name]
+		if !ok || i >= len(result.Totals[0].MetricValues) {
+			continue
+		}
+		if !wanted[h.Name] || (types[column] != bigquery.IntegerFieldType && types[column] != bigquery.FloatFieldType) {
+			continue
+		}
+		total, err := strconv.ParseFloat(result.Totals[0].MetricValues[i].Value, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid GA4 total for %s", h.Name)
+		}
+		out = append(out, reconcileColumn{metric: h.Name, column: column, total: total})
+	}
+	return out, nil
+}
+
+// compareTotal checks a BigQuery aggregate against a GA4 total within tolerance.
+func compareTotal(metric, column string, ga4Value, bqValue, tolerance float64) ReconcileResult {
+	diff := math.Abs(bqValue - ga4Value)
+	if ga4Value != 0 {
+		diff /= math.Abs(ga4Value)
+	}
+	return ReconcileResult{
+		Metric:     metric,
+		Column:     column,
+		GA4:        ga4Value,
+		BigQuery:   bqValue,
+		Difference: diff,
+		OK:         diff <= tolerance,
+	}
+}
+
+// reconcile compares the loaded BigQuery rows with the GA4 totals and row count, records
+// the results in ReconcileTable and fails when any is beyond RECONCILE.TOLERANCE.
+func (p *Pipeline) reconcile(ctx context.Context, report Report, result *ga.RunReportResponse, dest Destination) ([]ReconcileResult, error) {
+	columns, err := reconcileColumns(report, result, p.cfg.Reconcile.Metrics)
+	if err != nil {
+		return nil, err
+	}
+	values, err := p.aggregate(ctx, report, dest.Table, columns)
+	if err != nil {
+		return nil, err
+	}
+	tolerance := p.cfg.Reconcile.Tolerance
+	results := []ReconcileResult{compareTotal(RowCountMetric, "", float64(result.RowCount), values[0], tolerance)}
+	for i, c := range columns {
+		results = append(results, compareTotal(c.metric, c.column, c.total, values[i+1], tolerance))
+	}
+
+	if err := p.recordReconciliation(ctx, dest, results); err != nil {
+		return results, err
+	}
+	var failed []string
+	for _, r := range results {
+		if !r.OK {
+			failed = append(failed, fmt.Sprintf("%s: GA4 %g, BigQuery %g", r.Metric, r.GA4, r.BigQuery))
+		}
+	}
+	if len(failed) > 0 {
+		return results, errors.Errorf("%d value(s) differ by more than %g: %s", len(failed), tolerance, strings.Join(failed, "; "))
+	}
+	return results, nil
+}
+
+// aggregate returns COUNT(*) followed by the SUM of each column over the loaded date
+// range. The range is only applied when the report has a date column.
+func (p *Pipeline) aggregate(ctx context.Context, report Report, table string, columns []reconcileColumn) ([]float64, error) {
+	selects := []string{"COUNT(*)"}
+	for _, c := range columns {
+		selects = append(selects, fmt.Sprintf("SUM(`%s`)", c.column))
+	}
+	sql := fmt.Sprintf("SELECT %s FROM `%s.%s.%s`", strings.Join(selects, ", "), p.bqClient.Project(), p.cfg.DatasetID, table)
+
+	var params []bigquery.QueryParameter
+	if column := dateColumn(report); column != "" {
+		if start, end, err := p.resolvedRange(); err == nil {
+			// GA4 의 date dimension 은 YYYYMMDD 형식입니다.
+			sql += fmt.Sprintf(" WHERE `%s` BETWEEN @start AND @end", column)
+			params = []bigquery.QueryParameter{
+				{Name: "start", Value: strings.ReplaceAll(start, "-", "")},
+				{Name: "end", Value: strings.ReplaceAll(end, "-", "")},
+			}
+		}
+	}
+
+	q := p.bqClient.Query(sql)
+	q.Parameters = params
+	it, err := q.Read(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to run reconciliation query")
+	}
+	var row []bigquery.Value
+	if err := it.Next(&row); err != nil && err != iterator.Done {
+		return nil, errors.Wrap(err, "failed to read reconciliation query")
+	}
+	values := make([]float64, len(selects))
+	for i := range values {
+		if i < len(row) {
+			values[i] = toFloat(row[i])
+		}
+	}
+	return values, nil
+}
+
+func dateColumn(report Report) string {
+	sourcer, ok := report.(reports.ColumnSourcer)
+	if !ok {
+		return ""
+	}
+	for column, source := range sourcer.ColumnSources() {
+		if source == "date" {
+			return column
+		}
+	}
+	return ""
+}
+
+func toFloat(v bigquery.Value) float64 {
+	switch t := v.(type) {
+	case int64:
+		return float64(t)
+	case float64:
+		return t
+	default:
+		return 0
+	}
+}
+
+var reconcileSchema = bigquery.Schema{
+	{Name: "run_id", Type: bigquery.StringFieldType, Required: true},
+	{Name: "checked_at", Type: bigquery.TimestampFieldType, Required: true},
+	{Name: "property_id", Type: bigquery.StringFieldType, Required: true},
+	{Name: "report_type", Type: bigquery.StringFieldType, Required: true},
+	{Name: "table_name", Type: bigquery.StringFieldType, Required: true},
+	{Name: "start_date", Type: bigquery.StringFieldType},
+	{Name: "end_date", Type: bigquery.StringFieldType},
+	{Name: "metric", Type: bigquery.StringFieldType, Required: true},
+	{Name: "column_name", Type: bigquery.StringFieldType},
+	{Name: "ga4_value", Type: bigquery.FloatFieldType, Required: true},
+	{Name: "bigquery_value", Type: bigquery.FloatFieldType, Required: true},
+	{Name: "difference", Type: bigquery.FloatFieldType, Required: true},
+	{Name: "within_tolerance", Type: bigquery.BooleanFieldType, Required: true},
+}
+
+type reconcileRow struct {
+	dest      Destination
+	checkedAt time.Time
+	result    ReconcileResult
+}
+
+func (r reconcileRow) Save() (map[string]bigquery.Value, string, error) {
+	return map[string]bigquery.Value{
+		"run_id":           r.dest.RunID,
+		"checked_at":       r.checkedAt,
+		"property_id":      r.dest.PropertyID,
+		"report_type":      r.dest.ReportType,
+		"table_name":       r.dest.Table,
+		"start_date":       r.dest.StartDate,
+		"end_date":         r.dest.EndDate,
+		"metric":           r.result.Metric,
+		"column_name":      r.result.Column,
+		"ga4_value":        r.result.GA4,
+		"bigquery_value":   r.result.BigQuery,
+		"difference":       r.result.Difference,
+		"within_tolerance": r.result.OK,
+	}, bigquery.NoDedupeID, nil
+}
+
+func (p *Pipeline) recordReconciliation(ctx context.Context, dest Destination, results []ReconcileResult) error {
+	table := p.bqClient.Dataset(p.cfg.DatasetID).Table(ReconcileTable)
+	if err := table.Create(ctx, &bigquery.TableMetadata{
+		Schema: reconcileSchema,
+		Labels: p.labels(),
+	}); err != nil && !isAlreadyExists(err) {
+		return errors.Wrap(err, "failed to create reconciliation table")
+	}
+	now := time.Now()
+	var rows []bigquery.ValueSaver
+	for _, r := range results {
+		rows = append(rows, reconcileRow{dest: dest, checkedAt: now, result: r})
+	}
+	if err := table.Inserter().Put(ctx, rows); err != nil {
+		return errors.Wrap(err, "failed to record reconciliation")
+	}
+	return nil
+}
+
+func isAlreadyExists(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusConflict
+}
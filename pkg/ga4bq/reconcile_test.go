@@ -0,0 +1,77 @@
+package ga4bq
+
+import (
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+	ga "google.golang.org/api/analyticsdata/v1beta"
+)
+
+type metricReport struct{ fakeReport }
+
+func (metricReport) Schema() bigquery.Schema {
+	return bigquery.Schema{
+		{Name: "event_date", Type: bigquery.StringFieldType},
+		{Name: "event_count", Type: bigquery.IntegerFieldType},
+		{Name: "engagement_rate", Type: bigquery.FloatFieldType},
+		{Name: "active_users", Type: bigquery.IntegerFieldType},
+	}
+}
+
+func (metricReport) ColumnSources() map[string]string {
+	return map[string]string{"event_date": "date", "event_count": "eventCount", "engagement_rate": "engagementRate", "active_users": "activeUsers"}
+}
+
+func TestReconcileColumns(t *testing.T) {
+	result := &ga.RunReportResponse{
+		MetricHeaders: []*ga.MetricHeader{{Name: "eventCount"}, {Name: "engagementRate"}, {Name: "activeUsers"}},
+		Totals:        []*ga.Row{{MetricValues: []*ga.MetricValue{{Value: "42"}, {Value: "0.5"}, {Value: "7"}}}},
+	}
+	tests := []struct {
+		name    string
+		report  Report
+		metrics []string
+		want    []string
+	}{
+		{name: "additive metrics by default", report: metricReport{}, want: []string{"event_count"}},
+		{name: "configured metrics", report: metricReport{}, metrics: []string{"engagementRate"}, want: []string{"engagement_rate"}},
+		{name: "without sources", report: fakeReport{}, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := reconcileColumns(tt.report, result, tt.metrics)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d columns, want %v", len(got), tt.want)
+			}
+			for i, c := range got {
+				if c.column != tt.want[i] {
+					t.Errorf("column %d = %s, want %s", i, c.column, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCompareTotal(t *testing.T) {
+	tests := []struct {
+		name      string
+		ga4, bq   float64
+		tolerance float64
+		wantOK    bool
+	}{
+		{name: "equal", ga4: 100, bq: 100, wantOK: true},
+		{name: "within tolerance", ga4: 100, bq: 99, tolerance: 0.01, wantOK: true},
+		{name: "beyond tolerance", ga4: 100, bq: 98, tolerance: 0.01, wantOK: false},
+		{name: "zero in GA4", ga4: 0, bq: 1, tolerance: 0.5, wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := compareTotal("eventCount", "event_count", tt.ga4, tt.bq, tt.tolerance); got.OK != tt.wantOK {
+				t.Errorf("OK = %v (difference %g), want %v", got.OK, got.Difference, tt.wantOK)
+			}
+		})
+	}
+}
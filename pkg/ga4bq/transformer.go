@@ -0,0 +1,36 @@
+package ga4bq
+
+import (
+	"cloud.google.com/go/bigquery"
+	"github.com/pkg/errors"
+	ga "google.golang.org/api/analyticsdata/v1beta"
+)
+
+// TransformFunc turns a GA4 response into rows; Report.TransformFunc is one.
+type TransformFunc = func(result *ga.RunReportResponse) ([]bigquery.ValueSaver, error)
+
+// Transformer runs a report's TransformFunc over a response, e.g. to add enrichment
+// or validation around it.
+type Transformer interface {
+	TransformData(result *ga.RunReportResponse, transform TransformFunc) ([]bigquery.ValueSaver, error)
+}
+
+// GA4Transformer transforms data from Google Analytics
+type GA4Transformer struct {
+}
+
+func NewGA4Transformer() *GA4Transformer {
+	return &GA4Transformer{}
+}
+
+func (t GA4Transformer) TransformData(result *ga.RunReportResponse, transformer TransformFunc) ([]bigquery.ValueSaver, error) {
+	if result == nil {
+		return nil, errors.New("result is nil")
+	}
+
+	transformedData, err := transformer(result)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to transform data")
+	}
+	return transformedData, nil
+}